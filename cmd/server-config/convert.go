@@ -0,0 +1,110 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	config "github.com/nats-io/server-config"
+)
+
+func runConvert(args []string) error {
+	var (
+		configYaml string
+		typesDir   string
+		from       string
+		to         string
+		noSchema   bool
+	)
+
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: server-config convert [flags] -to conf|json|yaml [file]\n\n")
+		fs.PrintDefaults()
+	}
+	schemaFlags(fs, &configYaml, &typesDir)
+	fs.StringVar(&from, "from", "", "The input format, either conf, json, or yaml. Defaults to the file extension.")
+	fs.StringVar(&to, "to", "", "The output format, either conf, json, or yaml.")
+	fs.BoolVar(&noSchema, "noschema", false, "Convert values without using the schema.")
+	fs.Parse(args)
+
+	if to == "" || fs.NArg() > 1 {
+		fs.Usage()
+		return fmt.Errorf("expected an output format and at most one file")
+	}
+
+	var c *config.Config
+	if !noSchema {
+		var err error
+		c, err = loadConfig(configYaml, typesDir, "")
+		if err != nil {
+			return err
+		}
+	}
+
+	filename := "<stdin>"
+	var (
+		src []byte
+		err error
+	)
+	if fs.NArg() == 1 {
+		filename = fs.Arg(0)
+		src, err = os.ReadFile(filename)
+		if from == "" {
+			from = strings.TrimPrefix(filepath.Ext(filename), ".")
+		}
+	} else {
+		src, err = io.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		return err
+	}
+
+	out, err := convert(filename, src, from, to, c)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
+
+// convert converts the source between formats.
+func convert(filename string, src []byte, from, to string, c *config.Config) ([]byte, error) {
+	switch from {
+	case "conf":
+		doc, err := config.ParseConf(filename, src)
+		if err != nil {
+			return nil, err
+		}
+		if to != "conf" {
+			if err := resolveIncludes(doc); err != nil {
+				return nil, err
+			}
+		}
+		switch to {
+		case "conf":
+			return config.FormatConf(doc, nil), nil
+		case "json":
+			return config.ConfToJSON(doc, c)
+		case "yaml", "yml":
+			return config.ConfToYAML(doc, c)
+		}
+
+	case "json", "yaml", "yml":
+		if to != "conf" {
+			return nil, fmt.Errorf("cannot convert %s to %s", from, to)
+		}
+		if from == "json" {
+			return config.JSONToConf(filename, src, c)
+		}
+		return config.YAMLToConf(filename, src, c)
+
+	default:
+		return nil, fmt.Errorf("unknown input format %q", from)
+	}
+
+	return nil, fmt.Errorf("unknown output format %q", to)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	config "github.com/nats-io/server-config"
+)
+
+func runDiff(args []string) error {
+	var (
+		configYaml    string
+		typesDir      string
+		oldConfigYaml string
+		oldTypesDir   string
+		format        string
+	)
+
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: server-config diff -old-config config.yaml [flags]\n\n")
+		fs.PrintDefaults()
+	}
+	schemaFlags(fs, &configYaml, &typesDir)
+	fs.StringVar(&oldConfigYaml, "old-config", "", "The root config YAML file of the old revision.")
+	fs.StringVar(&oldTypesDir, "old-types", "", "The path to the types directory of the old revision. Defaults to the types directory next to the old config.")
+	fs.StringVar(&format, "format", "markdown", "The output format, either markdown or json.")
+	fs.Parse(args)
+
+	if oldConfigYaml == "" {
+		fs.Usage()
+		return fmt.Errorf("no old config specified")
+	}
+	if oldTypesDir == "" {
+		oldTypesDir = filepath.Join(filepath.Dir(oldConfigYaml), "types")
+	}
+
+	// The old revision may predate fields added to the schema format, so
+	// unknown fields are ignored rather than rejected.
+	paths, err := typePaths(oldTypesDir)
+	if err != nil {
+		return err
+	}
+	oc, err := config.ParseLenient(oldConfigYaml, paths)
+	if err != nil {
+		return err
+	}
+	nc, err := loadConfig(configYaml, typesDir, "")
+	if err != nil {
+		return err
+	}
+
+	changes := config.DiffConfigs(oc, nc)
+
+	switch format {
+	case "markdown":
+		return config.GenerateChangelog(os.Stdout, changes)
+
+	case "json":
+		jcs := make([]*jsonChange, len(changes))
+		for i, c := range changes {
+			jcs[i] = &jsonChange{
+				Kind:    c.Kind.String(),
+				Path:    c.Path,
+				Details: c.Details,
+			}
+		}
+		e := json.NewEncoder(os.Stdout)
+		e.SetIndent("", "  ")
+		return e.Encode(jcs)
+	}
+
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// jsonChange is the JSON representation of a change.
+type jsonChange struct {
+	Kind    string   `json:"kind"`
+	Path    string   `json:"path"`
+	Details []string `json:"details,omitempty"`
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	config "github.com/nats-io/server-config"
+)
+
+func runFmt(args []string) error {
+	var (
+		configYaml string
+		typesDir   string
+		write      bool
+		diff       bool
+		reorder    bool
+		normalize  bool
+	)
+
+	fs := flag.NewFlagSet("fmt", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: server-config fmt [flags] [file.conf...]\n\n")
+		fs.PrintDefaults()
+	}
+	schemaFlags(fs, &configYaml, &typesDir)
+	fs.BoolVar(&write, "w", false, "Write the result to the file instead of stdout.")
+	fs.BoolVar(&diff, "d", false, "Print diffs instead of the formatted files.")
+	fs.BoolVar(&reorder, "reorder", false, "Reorder keys to match the order of the properties in the schema.")
+	fs.BoolVar(&normalize, "normalize", false, "Rewrite aliases to the canonical property names, e.g. leaf to leafnodes.")
+	fs.Parse(args)
+
+	var c *config.Config
+	if reorder || normalize {
+		var err error
+		c, err = loadConfig(configYaml, typesDir, "")
+		if err != nil {
+			return err
+		}
+	}
+
+	if fs.NArg() == 0 {
+		if write {
+			return fmt.Errorf("cannot use -w with standard input")
+		}
+		src, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		return formatFile("<stdin>", src, c, reorder, normalize, false, diff)
+	}
+
+	for _, path := range fs.Args() {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := formatFile(path, src, c, reorder, normalize, write, diff); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// formatFile formats the source of a conf file and either prints it,
+// writes it back to the file, or prints the diff. The config is used to
+// reorder keys and rewrite aliases if enabled.
+func formatFile(path string, src []byte, c *config.Config, reorder, normalize, write, diff bool) error {
+	doc, err := config.ParseConf(path, src)
+	if err != nil {
+		return err
+	}
+	if normalize {
+		config.NormalizeKeys(c, doc)
+	}
+	if !reorder {
+		c = nil
+	}
+	out := config.FormatConf(doc, c)
+
+	if diff {
+		fmt.Fprint(os.Stdout, unifiedDiff(path+".orig", path, src, out))
+	}
+
+	if write {
+		if bytes.Equal(src, out) {
+			return nil
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, out, info.Mode().Perm())
+	}
+
+	if !diff {
+		_, err = os.Stdout.Write(out)
+	}
+	return err
+}
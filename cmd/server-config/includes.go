@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	config "github.com/nats-io/server-config"
+)
+
+func runIncludes(args []string) error {
+	fs := flag.NewFlagSet("includes", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: server-config includes file.conf...\n\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("no conf files specified")
+	}
+
+	for _, file := range fs.Args() {
+		doc, err := parseConfIncludes(file)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stdout, doc.Filename)
+		printIncludes(os.Stdout, config.Includes(doc), "")
+	}
+
+	return nil
+}
+
+// printIncludes prints the include graph as a tree, noting the position
+// of each include directive.
+func printIncludes(w io.Writer, incs []*config.Include, prefix string) {
+	for i, inc := range incs {
+		branch, indent := "├── ", "│   "
+		if i == len(incs)-1 {
+			branch, indent = "└── ", "    "
+		}
+		fmt.Fprintf(w, "%s%s%s (%s)\n", prefix, branch, inc.Doc.Filename, inc.Pos)
+		printIncludes(w, inc.Includes, prefix+indent)
+	}
+}
+
+// parseConfIncludes parses the conf file and resolves its includes.
+func parseConfIncludes(file string) (*config.Document, error) {
+	doc, err := config.ParseConfFile(file)
+	if err != nil {
+		return nil, err
+	}
+	if err := resolveIncludes(doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// resolveIncludes resolves the includes of the document, returning an
+// error listing every include that failed.
+func resolveIncludes(doc *config.Document) error {
+	var msgs []string
+	for _, d := range config.ResolveIncludes(doc) {
+		msgs = append(msgs, d.String())
+	}
+	if len(msgs) > 0 {
+		return errors.New(strings.Join(msgs, "\n"))
+	}
+	return nil
+}
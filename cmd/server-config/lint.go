@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	config "github.com/nats-io/server-config"
+)
+
+func runLint(args []string) error {
+	var (
+		configYaml string
+		typesDir   string
+	)
+
+	fs := flag.NewFlagSet("lint", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: server-config lint [flags]\n\n")
+		fs.PrintDefaults()
+	}
+	schemaFlags(fs, &configYaml, &typesDir)
+	fs.Parse(args)
+
+	paths, err := typePaths(typesDir)
+	if err != nil {
+		return err
+	}
+
+	errs := config.Lint(configYaml, paths)
+	for _, err := range errs {
+		fmt.Fprintln(os.Stdout, err)
+	}
+
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("1 problem found")
+	}
+	return fmt.Errorf("%d problems found", len(errs))
+}
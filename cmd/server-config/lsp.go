@@ -0,0 +1,570 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	config "github.com/nats-io/server-config"
+)
+
+func runLSP(args []string) error {
+	var (
+		configYaml    string
+		typesDir      string
+		serverVersion string
+	)
+
+	fs := flag.NewFlagSet("lsp", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: server-config lsp [flags]\n\n")
+		fmt.Fprintf(fs.Output(), "Runs a Language Server Protocol server for conf files over stdio.\n\n")
+		fs.PrintDefaults()
+	}
+	schemaFlags(fs, &configYaml, &typesDir)
+	versionFlag(fs, &serverVersion)
+	fs.Parse(args)
+
+	c, err := loadConfig(configYaml, typesDir, serverVersion)
+	if err != nil {
+		return err
+	}
+
+	s := &lspServer{
+		cfg:    c,
+		r:      bufio.NewReader(os.Stdin),
+		w:      os.Stdout,
+		docs:   make(map[string]string),
+		parsed: make(map[string]*lspParsed),
+	}
+	return s.serve()
+}
+
+// lspMessage is a JSON-RPC request, response, or notification.
+type lspMessage struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Result  any              `json:"result,omitempty"`
+	Error   *lspError        `json:"error,omitempty"`
+}
+
+type lspError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspLocation struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+type lspTextDocumentPosition struct {
+	TextDocument struct {
+		URI string `json:"uri"`
+	} `json:"textDocument"`
+	Position lspPosition `json:"position"`
+}
+
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+type lspCompletionItem struct {
+	Label         string         `json:"label"`
+	Kind          int            `json:"kind"`
+	Detail        string         `json:"detail,omitempty"`
+	Documentation *lspMarkupText `json:"documentation,omitempty"`
+	Deprecated    bool           `json:"deprecated,omitempty"`
+}
+
+type lspMarkupText struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+const (
+	lspMethodNotFound = -32601
+	lspInvalidParams  = -32602
+
+	lspSeverityError   = 1
+	lspSeverityWarning = 2
+
+	lspCompletionProperty = 10
+	lspCompletionValue    = 12
+
+	lspSyncFull = 1
+)
+
+// lspServer serves a single client, handling one message at a time.
+type lspServer struct {
+	cfg *config.Config
+	r   *bufio.Reader
+	w   io.Writer
+
+	// docs are the texts of the open documents by URI.
+	docs map[string]string
+
+	// parsed are the last documents parsed without syntax errors by URI,
+	// which hover and definition fall back to while the document is
+	// being edited.
+	parsed map[string]*lspParsed
+}
+
+// lspParsed is a parsed document along with the text it was parsed from.
+type lspParsed struct {
+	doc  *config.Document
+	text string
+}
+
+// serve reads and handles messages until the client exits.
+func (s *lspServer) serve() error {
+	for {
+		m, err := s.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if m.Method == "exit" {
+			return nil
+		}
+
+		result, rerr := s.handle(m)
+
+		// Notifications have no response.
+		if m.ID == nil {
+			continue
+		}
+		resp := &lspMessage{
+			JSONRPC: "2.0",
+			ID:      m.ID,
+			Result:  result,
+			Error:   rerr,
+		}
+		if result == nil && rerr == nil {
+			resp.Result = json.RawMessage("null")
+		}
+		if err := s.write(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// read reads a message framed by a Content-Length header.
+func (s *lspServer) read() (*lspMessage, error) {
+	h, err := textproto.NewReader(s.r).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(h.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("lsp: invalid Content-Length: %w", err)
+	}
+
+	b := make([]byte, n)
+	if _, err := io.ReadFull(s.r, b); err != nil {
+		return nil, err
+	}
+
+	var m lspMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("lsp: %w", err)
+	}
+	return &m, nil
+}
+
+// write writes a message framed by a Content-Length header.
+func (s *lspServer) write(m *lspMessage) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.w, "Content-Length: %d\r\n\r\n%s", len(b), b)
+	return err
+}
+
+// notify sends a notification to the client.
+func (s *lspServer) notify(method string, params any) error {
+	b, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return s.write(&lspMessage{
+		JSONRPC: "2.0",
+		Method:  method,
+		Params:  b,
+	})
+}
+
+// handle handles a request or notification, returning the result of a
+// request.
+func (s *lspServer) handle(m *lspMessage) (any, *lspError) {
+	switch m.Method {
+	case "initialize":
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync":   lspSyncFull,
+				"completionProvider": map[string]any{},
+				"hoverProvider":      true,
+				"definitionProvider": true,
+			},
+			"serverInfo": map[string]any{
+				"name": "server-config",
+			},
+		}, nil
+
+	case "shutdown", "initialized":
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var p struct {
+			TextDocument struct {
+				URI  string `json:"uri"`
+				Text string `json:"text"`
+			} `json:"textDocument"`
+		}
+		if err := json.Unmarshal(m.Params, &p); err != nil {
+			return nil, &lspError{Code: lspInvalidParams, Message: err.Error()}
+		}
+		s.docs[p.TextDocument.URI] = p.TextDocument.Text
+		s.publishDiagnostics(p.TextDocument.URI)
+		return nil, nil
+
+	case "textDocument/didChange":
+		var p struct {
+			TextDocument struct {
+				URI string `json:"uri"`
+			} `json:"textDocument"`
+			ContentChanges []struct {
+				Text string `json:"text"`
+			} `json:"contentChanges"`
+		}
+		if err := json.Unmarshal(m.Params, &p); err != nil {
+			return nil, &lspError{Code: lspInvalidParams, Message: err.Error()}
+		}
+		// With full sync, the last change is the whole text.
+		if n := len(p.ContentChanges); n > 0 {
+			s.docs[p.TextDocument.URI] = p.ContentChanges[n-1].Text
+		}
+		s.publishDiagnostics(p.TextDocument.URI)
+		return nil, nil
+
+	case "textDocument/didClose":
+		var p struct {
+			TextDocument struct {
+				URI string `json:"uri"`
+			} `json:"textDocument"`
+		}
+		if err := json.Unmarshal(m.Params, &p); err != nil {
+			return nil, &lspError{Code: lspInvalidParams, Message: err.Error()}
+		}
+		delete(s.docs, p.TextDocument.URI)
+		delete(s.parsed, p.TextDocument.URI)
+		s.notify("textDocument/publishDiagnostics", map[string]any{
+			"uri":         p.TextDocument.URI,
+			"diagnostics": []lspDiagnostic{},
+		})
+		return nil, nil
+
+	case "textDocument/completion":
+		var p lspTextDocumentPosition
+		if err := json.Unmarshal(m.Params, &p); err != nil {
+			return nil, &lspError{Code: lspInvalidParams, Message: err.Error()}
+		}
+		return s.completion(p), nil
+
+	case "textDocument/hover":
+		var p lspTextDocumentPosition
+		if err := json.Unmarshal(m.Params, &p); err != nil {
+			return nil, &lspError{Code: lspInvalidParams, Message: err.Error()}
+		}
+		return s.hover(p), nil
+
+	case "textDocument/definition":
+		var p lspTextDocumentPosition
+		if err := json.Unmarshal(m.Params, &p); err != nil {
+			return nil, &lspError{Code: lspInvalidParams, Message: err.Error()}
+		}
+		return s.definition(p), nil
+	}
+
+	if m.ID == nil {
+		return nil, nil
+	}
+	return nil, &lspError{Code: lspMethodNotFound, Message: fmt.Sprintf("method %q not found", m.Method)}
+}
+
+// parse parses the open document, resolving its includes and variables.
+// If the document has syntax errors, such as while typing, the last
+// document parsed without errors is returned, or nil if there is none.
+// Offsets within the document refer to the returned text.
+func (s *lspServer) parse(uri string) (*config.Document, string) {
+	text := s.docs[uri]
+	doc, err := config.ParseConf(uriPath(uri), []byte(text))
+	if err != nil {
+		if p := s.parsed[uri]; p != nil {
+			return p.doc, p.text
+		}
+		return nil, ""
+	}
+	config.ResolveIncludes(doc)
+	config.ResolveVariables(doc, nil)
+	s.parsed[uri] = &lspParsed{doc: doc, text: text}
+	return doc, text
+}
+
+// publishDiagnostics validates the document and publishes the diagnostics
+// within it.
+func (s *lspServer) publishDiagnostics(uri string) {
+	path := uriPath(uri)
+	text := s.docs[uri]
+
+	var diags []config.Diagnostic
+	doc, err := config.ParseConf(path, []byte(text))
+	if err != nil {
+		if pe, ok := err.(*config.ParseError); ok {
+			diags = append(diags, config.Diagnostic{Pos: pe.Pos, Message: pe.Msg})
+		} else {
+			diags = append(diags, config.Diagnostic{Message: err.Error()})
+		}
+	} else {
+		diags = append(diags, config.ResolveIncludes(doc)...)
+		diags = append(diags, config.ResolveVariables(doc, nil)...)
+		diags = append(diags, config.ValidateDocument(s.cfg, doc)...)
+	}
+
+	lds := []lspDiagnostic{}
+	for _, d := range diags {
+		// Diagnostics within included files are reported when they
+		// are opened.
+		if d.Pos.Filename != "" && d.Pos.Filename != path {
+			continue
+		}
+
+		sev := lspSeverityError
+		if d.Severity == config.SeverityWarning {
+			sev = lspSeverityWarning
+		}
+
+		var r lspRange
+		if d.Pos.IsValid() {
+			r.Start = lspPositionAt(text, d.Pos.Offset)
+			r.End = lspPositionAt(text, tokenEnd(text, d.Pos.Offset))
+		}
+		lds = append(lds, lspDiagnostic{
+			Range:    r,
+			Severity: sev,
+			Source:   "server-config",
+			Message:  d.Message,
+		})
+	}
+
+	s.notify("textDocument/publishDiagnostics", map[string]any{
+		"uri":         uri,
+		"diagnostics": lds,
+	})
+}
+
+// completion returns the property names or values at the position.
+func (s *lspServer) completion(p lspTextDocumentPosition) []lspCompletionItem {
+	text := s.docs[p.TextDocument.URI]
+	offset := lspOffset(text, p.Position)
+
+	items := []lspCompletionItem{}
+	for _, it := range config.Complete(s.cfg, []byte(text), offset) {
+		x := lspCompletionItem{
+			Label:  it.Label,
+			Kind:   lspCompletionProperty,
+			Detail: it.Detail,
+		}
+		if it.Value {
+			x.Kind = lspCompletionValue
+		} else if it.Property != nil {
+			x.Documentation = &lspMarkupText{Kind: "markdown", Value: hoverText(it.Property)}
+			x.Deprecated = it.Property.Deprecation != ""
+		}
+		items = append(items, x)
+	}
+	return items
+}
+
+// hover returns the documentation of the property at the position.
+func (s *lspServer) hover(p lspTextDocumentPosition) any {
+	doc, text := s.parse(p.TextDocument.URI)
+	if doc == nil {
+		return nil
+	}
+
+	n := config.NodeAt(s.cfg, doc, lspOffset(text, p.Position))
+	if n == nil || n.Property == nil {
+		return nil
+	}
+
+	start := n.Entry.KeyPos.Offset
+	end := tokenEnd(text, start)
+	if n.Value != nil && n.Value.Kind != config.MapValue && n.Value.Kind != config.ArrayValue {
+		start, end = n.Value.Pos.Offset, n.Value.End.Offset
+	}
+
+	return map[string]any{
+		"contents": lspMarkupText{Kind: "markdown", Value: hoverText(n.Property)},
+		"range": lspRange{
+			Start: lspPositionAt(text, start),
+			End:   lspPositionAt(text, end),
+		},
+	}
+}
+
+// definition returns the location a variable or include refers to.
+func (s *lspServer) definition(p lspTextDocumentPosition) any {
+	doc, text := s.parse(p.TextDocument.URI)
+	if doc == nil {
+		return nil
+	}
+
+	pos, ok := config.Definition(doc, lspOffset(text, p.Position))
+	if !ok {
+		return nil
+	}
+
+	uri := pathURI(pos.Filename)
+	target, ok := s.docs[uri]
+	if !ok {
+		b, err := os.ReadFile(pos.Filename)
+		if err != nil {
+			return nil
+		}
+		target = string(b)
+	}
+
+	at := lspPositionAt(target, pos.Offset)
+	return lspLocation{
+		URI:   uri,
+		Range: lspRange{Start: at, End: at},
+	}
+}
+
+// hoverText describes a property as Markdown.
+func hoverText(p *config.Property) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**%s**", p.Name)
+	if len(p.Aliases) > 0 {
+		fmt.Fprintf(&b, " (aliases: %s)", strings.Join(p.Aliases, ", "))
+	}
+	b.WriteString("\n\n")
+
+	if p.Description != "" {
+		fmt.Fprintf(&b, "%s\n\n", p.Description)
+	}
+
+	if p.Default != nil {
+		d, _ := json.Marshal(p.Default)
+		fmt.Fprintf(&b, "- Default: `%s`\n", d)
+	}
+	if p.Reloadable {
+		b.WriteString("- Reloadable: yes")
+	} else {
+		b.WriteString("- Reloadable: no")
+	}
+	if p.ReloadableNote != "" {
+		fmt.Fprintf(&b, " (%s)", strings.Join(strings.Fields(p.ReloadableNote), " "))
+	}
+	b.WriteString("\n")
+	if p.Deprecation != "" {
+		fmt.Fprintf(&b, "- Deprecated: %s\n", strings.Join(strings.Fields(p.Deprecation), " "))
+	}
+
+	return b.String()
+}
+
+// uriPath returns the file path of a file URI.
+func uriPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+// pathURI returns the file URI of a path.
+func pathURI(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	u := url.URL{Scheme: "file", Path: filepath.ToSlash(path)}
+	return u.String()
+}
+
+// lspOffset converts an LSP position, whose character is in UTF-16 code
+// units, to a byte offset in the text.
+func lspOffset(text string, p lspPosition) int {
+	off := 0
+	for line := 0; line < p.Line; line++ {
+		i := strings.IndexByte(text[off:], '\n')
+		if i < 0 {
+			return len(text)
+		}
+		off += i + 1
+	}
+
+	for n := 0; n < p.Character && off < len(text) && text[off] != '\n'; {
+		r, size := utf8.DecodeRuneInString(text[off:])
+		n += len(utf16.Encode([]rune{r}))
+		off += size
+	}
+	return off
+}
+
+// lspPositionAt converts a byte offset in the text to an LSP position.
+func lspPositionAt(text string, offset int) lspPosition {
+	if offset > len(text) {
+		offset = len(text)
+	}
+	var p lspPosition
+	lineStart := strings.LastIndexByte(text[:offset], '\n') + 1
+	p.Line = strings.Count(text[:lineStart], "\n")
+	for _, r := range text[lineStart:offset] {
+		p.Character += len(utf16.Encode([]rune{r}))
+	}
+	return p
+}
+
+// tokenEnd returns the offset of the end of the word starting at the
+// offset, used to highlight the key or value of a diagnostic.
+func tokenEnd(text string, offset int) int {
+	i := offset
+	for i < len(text) && !strings.ContainsRune(" \t\r\n:=,{}[]", rune(text[i])) {
+		i++
+	}
+	if i == offset && i < len(text) {
+		i++
+	}
+	return i
+}
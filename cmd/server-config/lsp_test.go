@@ -0,0 +1,81 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	config "github.com/nats-io/server-config"
+)
+
+func TestLSPHoverWithSyntaxErrors(t *testing.T) {
+	c, err := config.DefaultConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &lspServer{
+		cfg:    c,
+		docs:   make(map[string]string),
+		parsed: make(map[string]*lspParsed),
+	}
+
+	const uri = "file:///tmp/nats.conf"
+	var p lspTextDocumentPosition
+	p.TextDocument.URI = uri
+	p.Position = lspPosition{Line: 0, Character: 1}
+
+	hoverValue := func() string {
+		h, ok := s.hover(p).(map[string]any)
+		if !ok {
+			return ""
+		}
+		return h["contents"].(lspMarkupText).Value
+	}
+
+	// Without a successful parse there is nothing to fall back to.
+	s.docs[uri] = "port: 4222\njetstream {\n"
+	if v := hoverValue(); v != "" {
+		t.Fatalf("hover before a successful parse = %q, want none", v)
+	}
+
+	s.docs[uri] = "port: 4222\n"
+	if v := hoverValue(); !strings.HasPrefix(v, "**port**") {
+		t.Fatalf("hover = %q, want the port property", v)
+	}
+
+	// While typing, the last successful parse is used.
+	s.docs[uri] = "port: 4222\njetstream {\n"
+	if v := hoverValue(); !strings.HasPrefix(v, "**port**") {
+		t.Errorf("hover with a syntax error = %q, want the port property", v)
+	}
+}
+
+func TestLSPDefinitionWithSyntaxErrors(t *testing.T) {
+	c, err := config.DefaultConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &lspServer{
+		cfg:    c,
+		docs:   make(map[string]string),
+		parsed: make(map[string]*lspParsed),
+	}
+
+	const uri = "file:///tmp/nats.conf"
+	var p lspTextDocumentPosition
+	p.TextDocument.URI = uri
+	p.Position = lspPosition{Line: 1, Character: 7}
+
+	s.docs[uri] = "PORT: 4222\nport: $PORT\n"
+	if _, ok := s.definition(p).(lspLocation); !ok {
+		t.Fatal("no definition found for $PORT")
+	}
+
+	s.docs[uri] = "PORT: 4222\nport: $PORT\ndebug: [\n"
+	loc, ok := s.definition(p).(lspLocation)
+	if !ok {
+		t.Fatal("no definition found for $PORT with a syntax error")
+	}
+	if loc.Range.Start != (lspPosition{Line: 0, Character: 0}) {
+		t.Errorf("definition at %+v, want the start of the file", loc.Range.Start)
+	}
+}
@@ -16,11 +16,37 @@ func main() {
 	}
 }
 
+// commands are the subcommands operating on conf files. Without a
+// subcommand, the flags select the output format to generate.
+var commands = map[string]func(args []string) error{
+	"convert":  runConvert,
+	"diff":     runDiff,
+	"fmt":      runFmt,
+	"includes": runIncludes,
+	"lint":     runLint,
+	"lsp":      runLSP,
+	"map-test": runMapTest,
+	"migrate":  runMigrate,
+	"reload":   runReload,
+	"validate": runValidate,
+}
+
 func run() error {
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			return cmd(os.Args[2:])
+		}
+	}
+
 	var (
 		configYaml    string
 		typesDir      string
+		serverVersion string
 		genMarkdown   bool
+		genConf       bool
+		genJSONSchema bool
+		genGo         bool
+		goPackage     string
 		dirName       string
 		basePath      string
 		useRelative   bool
@@ -29,8 +55,13 @@ func run() error {
 		breadcrumbs   bool
 	)
 
-	flag.StringVar(&configYaml, "config", "config.yaml", "The root config YAML file.")
-	flag.StringVar(&typesDir, "types", "types", "The path to the types directory.")
+	schemaFlags(flag.CommandLine, &configYaml, &typesDir)
+	versionFlag(flag.CommandLine, &serverVersion)
+
+	flag.BoolVar(&genConf, "conf", false, "Generate a reference conf file on stdout.")
+	flag.BoolVar(&genJSONSchema, "jsonschema", false, "Generate a JSON Schema of the config on stdout.")
+	flag.BoolVar(&genGo, "go", false, "Generate Go structs of the config on stdout.")
+	flag.StringVar(&goPackage, "package", "config", "The package name of the generated Go code.")
 
 	// Markdown options
 	flag.BoolVar(&genMarkdown, "markdown", false, "Generate markdown files for the reference docs.")
@@ -43,16 +74,7 @@ func run() error {
 
 	flag.Parse()
 
-	var paths []string
-	entries, err := os.ReadDir(typesDir)
-	if err != nil {
-		return fmt.Errorf("read dir: %w", err)
-	}
-	for _, e := range entries {
-		paths = append(paths, filepath.Join(typesDir, e.Name()))
-	}
-
-	c, err := config.Parse(configYaml, paths)
+	c, err := loadConfig(configYaml, typesDir, serverVersion)
 	if err != nil {
 		return err
 	}
@@ -69,7 +91,69 @@ func run() error {
 
 		return config.GenerateMarkdown(c, dirName, &mc)
 
+	case genConf:
+		return config.GenerateConfig(os.Stdout, c.Sections)
+
+	case genJSONSchema:
+		b, err := config.GenerateJSONSchema(c)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(os.Stdout, "%s\n", b)
+		return err
+
+	case genGo:
+		return config.GenerateGo(os.Stdout, c, &config.GoConfig{
+			Package: goPackage,
+		})
+
 	default:
 		return fmt.Errorf("no output format specified")
 	}
 }
+
+// schemaFlags defines the flags locating the config and type definitions.
+func schemaFlags(fs *flag.FlagSet, configYaml, typesDir *string) {
+	fs.StringVar(configYaml, "config", "config.yaml", "The root config YAML file.")
+	fs.StringVar(typesDir, "types", "types", "The path to the types directory.")
+}
+
+// versionFlag defines the flag selecting the server version to filter
+// the config by.
+func versionFlag(fs *flag.FlagSet, serverVersion *string) {
+	fs.StringVar(serverVersion, "server-version", "", "Only include properties valid for the nats-server version, e.g. 2.9.0.")
+}
+
+// loadConfig parses the config using all type definition files in the
+// types directory. If the server version is set, the config is filtered
+// to the properties valid for that version.
+func loadConfig(configYaml, typesDir, serverVersion string) (*config.Config, error) {
+	paths, err := typePaths(typesDir)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := config.Parse(configYaml, paths)
+	if err != nil {
+		return nil, err
+	}
+
+	if serverVersion != "" {
+		return config.FilterVersion(c, serverVersion)
+	}
+	return c, nil
+}
+
+// typePaths returns the paths of the type definition files in the types
+// directory.
+func typePaths(typesDir string) ([]string, error) {
+	var paths []string
+	entries, err := os.ReadDir(typesDir)
+	if err != nil {
+		return nil, fmt.Errorf("read dir: %w", err)
+	}
+	for _, e := range entries {
+		paths = append(paths, filepath.Join(typesDir, e.Name()))
+	}
+	return paths, nil
+}
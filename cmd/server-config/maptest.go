@@ -0,0 +1,130 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	config "github.com/nats-io/server-config"
+)
+
+func runMapTest(args []string) error {
+	var (
+		configYaml string
+		typesDir   string
+		account    string
+		cluster    string
+		examples   bool
+	)
+
+	fs := flag.NewFlagSet("map-test", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: server-config map-test [flags] file.conf subject...\n")
+		fmt.Fprintf(fs.Output(), "       server-config map-test -examples\n\n")
+		fmt.Fprintf(fs.Output(), "Prints the destinations the subjects are mapped to by the mappings of the\nconf file. With -examples, the tests of the mapping examples of the schema\nare run instead.\n\n")
+		fs.PrintDefaults()
+	}
+	schemaFlags(fs, &configYaml, &typesDir)
+	fs.StringVar(&account, "account", "", "Use the mappings of the account rather than the top-level mappings.")
+	fs.StringVar(&cluster, "cluster", "", "Only print the destinations that apply to a server in the cluster.")
+	fs.BoolVar(&examples, "examples", false, "Run the tests of the mapping examples of the schema.")
+	fs.Parse(args)
+
+	c, err := loadConfig(configYaml, typesDir, "")
+	if err != nil {
+		return err
+	}
+
+	if examples {
+		return runMappingExamples(c)
+	}
+
+	if fs.NArg() < 2 {
+		fs.Usage()
+		return fmt.Errorf("a conf file and at least one subject are required")
+	}
+
+	path := fs.Arg(0)
+	doc, err := config.ParseConfFile(path)
+	if err != nil {
+		return err
+	}
+	if err := resolveIncludes(doc); err != nil {
+		return err
+	}
+	for _, d := range config.ResolveVariables(doc, nil) {
+		if d.Severity == config.SeverityError {
+			return fmt.Errorf("%s", d)
+		}
+	}
+
+	ms, err := config.DocumentMappings(c, doc, account)
+	if err != nil {
+		return err
+	}
+	if len(ms) == 0 {
+		return fmt.Errorf("%s: no mappings defined", path)
+	}
+
+	for _, subject := range fs.Args()[1:] {
+		if subject == "" || strings.ContainsAny(subject, "*> \t") {
+			return fmt.Errorf("invalid subject %q: a subject without wildcards is required", subject)
+		}
+		m := config.MatchMapping(ms, subject)
+		if m == nil {
+			fmt.Printf("%s: not mapped\n", subject)
+			continue
+		}
+
+		fmt.Printf("%s: mapped by %q (%s)\n", subject, m.Source, m.Pos)
+		dests := m.Destinations
+		if cluster != "" {
+			dests = m.ClusterDestinations(cluster)
+		}
+		for _, d := range dests {
+			s, err := d.Apply(subject)
+			if err != nil {
+				return err
+			}
+			line := fmt.Sprintf("  %s  weight %d%%", s, d.Weight)
+			if d.Cluster != "" {
+				line += fmt.Sprintf("  cluster %s", d.Cluster)
+			}
+			fmt.Println(line)
+		}
+	}
+
+	return nil
+}
+
+// runMappingExamples runs the tests of the examples of the types whose
+// examples define mappings, e.g. `mappings`.
+func runMappingExamples(c *config.Config) error {
+	var (
+		runs     int
+		failures []string
+	)
+
+	for _, e := range config.MappingExamples(c) {
+		runs += len(e.Tests)
+
+		fs, err := config.RunMappingExample(e)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("example %q: %s", e.Label, err))
+			continue
+		}
+		for _, f := range fs {
+			failures = append(failures, fmt.Sprintf("example %q: %s", e.Label, f))
+		}
+	}
+
+	for _, f := range failures {
+		fmt.Fprintln(os.Stderr, f)
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%d failures in %d example tests", len(failures), runs)
+	}
+	fmt.Printf("%d example tests passed\n", runs)
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+
+	config "github.com/nats-io/server-config"
+)
+
+func runMigrate(args []string) error {
+	var (
+		configYaml    string
+		typesDir      string
+		serverVersion string
+		write         bool
+	)
+
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: server-config migrate [flags] file.conf...\n\n")
+		fmt.Fprintf(fs.Output(), "Moves deprecated keys to their replacement. Without -w, the changes are\nprinted as a unified diff.\n\n")
+		fs.PrintDefaults()
+	}
+	schemaFlags(fs, &configYaml, &typesDir)
+	versionFlag(fs, &serverVersion)
+	fs.BoolVar(&write, "w", false, "Write the result to the file instead of printing a diff.")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("no conf files specified")
+	}
+
+	c, err := loadConfig(configYaml, typesDir, serverVersion)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range fs.Args() {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		out, migs, err := config.Migrate(c, path, src)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range migs {
+			switch {
+			case m.Applied:
+				fmt.Fprintf(os.Stderr, "%s: moved %q to %q\n", m.Pos, m.Path, m.Property.ReplacedBy)
+			default:
+				fmt.Fprintf(os.Stderr, "%s: %q is deprecated, not migrated: %s\n", m.Pos, m.Path, m.Note)
+			}
+		}
+
+		if bytes.Equal(src, out) {
+			continue
+		}
+
+		if !write {
+			fmt.Fprint(os.Stdout, unifiedDiff(path+".orig", path, src, out))
+			continue
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, out, info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
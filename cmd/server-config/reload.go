@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	config "github.com/nats-io/server-config"
+)
+
+func runReload(args []string) error {
+	var (
+		configYaml    string
+		typesDir      string
+		serverVersion string
+		format        string
+	)
+
+	fs := flag.NewFlagSet("reload", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: server-config reload [flags] running.conf proposed.conf\n\n")
+		fs.PrintDefaults()
+	}
+	schemaFlags(fs, &configYaml, &typesDir)
+	versionFlag(fs, &serverVersion)
+	fs.StringVar(&format, "format", "text", "The output format, either text or json.")
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return fmt.Errorf("expected the running and proposed conf files")
+	}
+
+	c, err := loadConfig(configYaml, typesDir, serverVersion)
+	if err != nil {
+		return err
+	}
+
+	running, err := parseConfIncludes(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	proposed, err := parseConfIncludes(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	for _, doc := range []*config.Document{running, proposed} {
+		for _, d := range config.ResolveVariables(doc, nil) {
+			if d.Severity == config.SeverityError {
+				return fmt.Errorf("%s", d)
+			}
+		}
+	}
+
+	changes := config.ReloadImpact(c, running, proposed)
+	if err := printReloadChanges(os.Stdout, format, changes); err != nil {
+		return err
+	}
+
+	var n int
+	for _, c := range changes {
+		if !c.Reloadable {
+			n++
+		}
+	}
+	switch n {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("restart required for 1 change")
+	}
+	return fmt.Errorf("restart required for %d changes", n)
+}
+
+// jsonReloadChange is the JSON representation of a reload change.
+type jsonReloadChange struct {
+	Kind       string `json:"kind"`
+	Path       string `json:"path"`
+	File       string `json:"file"`
+	Line       int    `json:"line,omitempty"`
+	Column     int    `json:"column,omitempty"`
+	Reloadable bool   `json:"reloadable"`
+	Note       string `json:"note,omitempty"`
+}
+
+// printReloadChanges writes the changes either as lines classifying each
+// change or as a JSON array.
+func printReloadChanges(w io.Writer, format string, changes []*config.ReloadChange) error {
+	switch format {
+	case "text":
+		if len(changes) == 0 {
+			fmt.Fprintln(w, "no changes")
+			return nil
+		}
+		for _, c := range changes {
+			action := "reload"
+			if !c.Reloadable {
+				action = "restart"
+			}
+			line := fmt.Sprintf("%s: %s: %s %s", c.Pos, action, c.Path, c.Kind)
+			if c.Note != "" {
+				line += fmt.Sprintf(" (%s)", c.Note)
+			}
+			fmt.Fprintln(w, line)
+		}
+		return nil
+
+	case "json":
+		jcs := make([]*jsonReloadChange, len(changes))
+		for i, c := range changes {
+			jcs[i] = &jsonReloadChange{
+				Kind:       c.Kind.String(),
+				Path:       c.Path,
+				File:       c.Pos.Filename,
+				Line:       c.Pos.Line,
+				Column:     c.Pos.Column,
+				Reloadable: c.Reloadable,
+				Note:       c.Note,
+			}
+		}
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return e.Encode(jcs)
+	}
+
+	return fmt.Errorf("unknown format %q", format)
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	config "github.com/nats-io/server-config"
+)
+
+func runValidate(args []string) error {
+	var (
+		configYaml    string
+		typesDir      string
+		serverVersion string
+		format        string
+	)
+
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: server-config validate [flags] file.conf...\n\n")
+		fs.PrintDefaults()
+	}
+	schemaFlags(fs, &configYaml, &typesDir)
+	versionFlag(fs, &serverVersion)
+	fs.StringVar(&format, "format", "text", "The output format, either text or json.")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return fmt.Errorf("no conf files specified")
+	}
+
+	c, err := loadConfig(configYaml, typesDir, serverVersion)
+	if err != nil {
+		return err
+	}
+
+	var diags []config.Diagnostic
+	for _, file := range fs.Args() {
+		diags = append(diags, config.Validate(c, file)...)
+	}
+
+	if err := printDiagnostics(os.Stdout, format, diags); err != nil {
+		return err
+	}
+
+	return diagnosticsError(diags)
+}
+
+// jsonDiagnostic is the JSON representation of a diagnostic.
+type jsonDiagnostic struct {
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+	Severity string `json:"severity"`
+	Path     string `json:"path,omitempty"`
+	Message  string `json:"message"`
+}
+
+// printDiagnostics writes the diagnostics either as compiler-style lines
+// or as a JSON array.
+func printDiagnostics(w io.Writer, format string, diags []config.Diagnostic) error {
+	switch format {
+	case "text":
+		for _, d := range diags {
+			fmt.Fprintln(w, d)
+		}
+		return nil
+
+	case "json":
+		jds := make([]*jsonDiagnostic, len(diags))
+		for i, d := range diags {
+			jds[i] = &jsonDiagnostic{
+				File:     d.Pos.Filename,
+				Line:     d.Pos.Line,
+				Column:   d.Pos.Column,
+				Severity: d.Severity.String(),
+				Path:     d.Path,
+				Message:  d.Message,
+			}
+		}
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		return e.Encode(jds)
+	}
+
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// diagnosticsError returns an error summarizing the number of errors, if
+// any, so the command exits with a non-zero status.
+func diagnosticsError(diags []config.Diagnostic) error {
+	var n int
+	for _, d := range diags {
+		if d.Severity == config.SeverityError {
+			n++
+		}
+	}
+	switch n {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("1 error found")
+	}
+	return fmt.Errorf("%d errors found", n)
+}
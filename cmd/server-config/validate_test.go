@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	config "github.com/nats-io/server-config"
+)
+
+var testDiagnostics = []config.Diagnostic{
+	{
+		Pos:      config.Pos{Filename: "nats.conf", Line: 1, Column: 1},
+		Severity: config.SeverityError,
+		Path:     "prot",
+		Message:  `unknown property "prot", did you mean "port"?`,
+	},
+	{
+		Pos:      config.Pos{Filename: "nats.conf", Line: 2, Column: 1},
+		Severity: config.SeverityWarning,
+		Path:     "store_dir",
+		Message:  `property "store_dir" is deprecated`,
+	},
+}
+
+func TestPrintDiagnostics(t *testing.T) {
+	var b bytes.Buffer
+	if err := printDiagnostics(&b, "text", testDiagnostics); err != nil {
+		t.Fatal(err)
+	}
+	const want = `nats.conf:1:1: error: unknown property "prot", did you mean "port"?
+nats.conf:2:1: warning: property "store_dir" is deprecated
+`
+	if b.String() != want {
+		t.Errorf("text diagnostics =\n%s\nwant\n%s", b.String(), want)
+	}
+
+	b.Reset()
+	if err := printDiagnostics(&b, "json", testDiagnostics); err != nil {
+		t.Fatal(err)
+	}
+	var jds []*jsonDiagnostic
+	if err := json.Unmarshal(b.Bytes(), &jds); err != nil {
+		t.Fatal(err)
+	}
+	if len(jds) != 2 {
+		t.Fatalf("got %d JSON diagnostics, want 2", len(jds))
+	}
+	want0 := jsonDiagnostic{File: "nats.conf", Line: 1, Column: 1, Severity: "error", Path: "prot", Message: testDiagnostics[0].Message}
+	if *jds[0] != want0 {
+		t.Errorf("JSON diagnostic = %+v, want %+v", *jds[0], want0)
+	}
+
+	// No diagnostics is an empty array rather than null.
+	b.Reset()
+	if err := printDiagnostics(&b, "json", nil); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "[]\n" {
+		t.Errorf("JSON without diagnostics = %q, want []", b.String())
+	}
+
+	if err := printDiagnostics(&b, "xml", nil); err == nil {
+		t.Error("no error for an unknown format")
+	}
+}
+
+func TestDiagnosticsError(t *testing.T) {
+	if err := diagnosticsError(testDiagnostics[1:]); err != nil {
+		t.Errorf("warnings only: %v, want no error", err)
+	}
+	if err := diagnosticsError(testDiagnostics); err == nil || err.Error() != "1 error found" {
+		t.Errorf("diagnosticsError() = %v, want 1 error found", err)
+	}
+	errs := append(testDiagnostics[:1:1], testDiagnostics[0])
+	if err := diagnosticsError(errs); err == nil || err.Error() != "2 errors found" {
+		t.Errorf("diagnosticsError() = %v, want 2 errors found", err)
+	}
+}
@@ -0,0 +1,540 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ValueKind is the kind of a parsed conf value.
+type ValueKind int
+
+const (
+	StringValue ValueKind = iota
+	IntegerValue
+	FloatValue
+	BoolValue
+	VariableValue
+	MapValue
+	ArrayValue
+)
+
+func (k ValueKind) String() string {
+	switch k {
+	case StringValue:
+		return "string"
+	case IntegerValue:
+		return "integer"
+	case FloatValue:
+		return "float"
+	case BoolValue:
+		return "boolean"
+	case VariableValue:
+		return "variable"
+	case MapValue:
+		return "map"
+	case ArrayValue:
+		return "array"
+	}
+	return "unknown"
+}
+
+// Document is a parsed NATS server conf file.
+type Document struct {
+	// Filename of the conf file, if any.
+	Filename string
+
+	// Root is the top-level map of the file which, unlike nested maps,
+	// is not enclosed in braces.
+	Root *Value
+}
+
+// Comment is a `#` or `//` comment, including the comment marker.
+type Comment struct {
+	Pos  Pos
+	Text string
+
+	// BlankBefore is true if the comment is preceded by an empty line.
+	BlankBefore bool
+
+	// BlankAfter is true if the comment is followed by an empty line.
+	BlankAfter bool
+}
+
+// Entry is a key-value pair within a map or an include directive.
+type Entry struct {
+	// Key as written, without quotes. Note, keys are matched
+	// case-insensitively by the server.
+	Key string
+
+	// KeyPos is the position of the key.
+	KeyPos Pos
+
+	// KeyQuote is the quote character used for the key, if any.
+	KeyQuote byte
+
+	// Sep is the separator used between the key and value, which is
+	// one of `=`, `:`, or empty if only whitespace is used.
+	Sep string
+
+	// Value of the entry. For include directives, this is the path.
+	Value *Value
+
+	// Include is true if this entry is an `include` directive.
+	Include bool
+
+	// Included is the parsed file of an include directive once the
+	// includes have been resolved.
+	Included *Document
+
+	// Comments are the comments on the lines preceding the entry.
+	Comments []*Comment
+
+	// LineComment is a comment on the same line following the entry.
+	LineComment *Comment
+
+	// BlankBefore is true if the entry, or its preceding comments, is
+	// preceded by an empty line.
+	BlankBefore bool
+}
+
+// Value is a parsed conf value. Which fields are set depends on the kind.
+type Value struct {
+	Kind ValueKind
+
+	// Pos is the position of the start of the value.
+	Pos Pos
+
+	// End is the position immediately after the value.
+	End Pos
+
+	// Raw is the source text of a scalar value, e.g. `1KB` or `"foo"`.
+	Raw string
+
+	// Quote is the quote character used for a string, if any. Block
+	// strings use `(` as the quote character.
+	Quote byte
+
+	// Str is the decoded string or the name of a variable.
+	Str string
+
+	// Int is the integer value with any unit suffix applied.
+	Int int64
+
+	// Float is the floating-point value.
+	Float float64
+
+	// Bool is the boolean value.
+	Bool bool
+
+	// Resolved is the value a variable refers to once the variables of
+	// the document have been resolved.
+	Resolved *Value
+
+	// Def is the entry defining a variable once the variables of the
+	// document have been resolved. It is nil for environment variables.
+	Def *Entry
+
+	// Entries are the ordered entries of a map.
+	Entries []*Entry
+
+	// Elems are the elements of an array.
+	Elems []*Value
+
+	// Comments preceding the value. This only applies to array elements.
+	Comments []*Comment
+
+	// LineComment is a comment on the same line following the value. This
+	// only applies to array elements.
+	LineComment *Comment
+
+	// TrailingComments are comments following the last entry or element
+	// of a map or array.
+	TrailingComments []*Comment
+}
+
+var (
+	integerRe = regexp.MustCompile(`^([-+]?[0-9]+)([a-zA-Z]*)$`)
+	floatRe   = regexp.MustCompile(`^[-+]?([0-9]+\.[0-9]*|\.[0-9]+)([eE][-+]?[0-9]+)?$`)
+)
+
+// unitMultipliers are the supported integer suffixes. A suffix having a
+// trailing `b`, such as `KB`, denotes powers of 1024.
+var unitMultipliers = map[string]int64{
+	"":    1,
+	"k":   1000,
+	"kb":  1 << 10,
+	"ki":  1 << 10,
+	"kib": 1 << 10,
+	"m":   1000 * 1000,
+	"mb":  1 << 20,
+	"mi":  1 << 20,
+	"mib": 1 << 20,
+	"g":   1000 * 1000 * 1000,
+	"gb":  1 << 30,
+	"gi":  1 << 30,
+	"gib": 1 << 30,
+	"t":   1000 * 1000 * 1000 * 1000,
+	"tb":  1 << 40,
+	"ti":  1 << 40,
+	"tib": 1 << 40,
+	"p":   1000 * 1000 * 1000 * 1000 * 1000,
+	"pb":  1 << 50,
+	"pi":  1 << 50,
+	"pib": 1 << 50,
+	"e":   1000 * 1000 * 1000 * 1000 * 1000 * 1000,
+	"eb":  1 << 60,
+	"ei":  1 << 60,
+	"eib": 1 << 60,
+}
+
+// parseInteger parses an integer with an optional unit suffix. The
+// boolean is false if the text is not an integer.
+func parseInteger(s string) (int64, bool, error) {
+	m := integerRe.FindStringSubmatch(s)
+	if m == nil {
+		return 0, false, nil
+	}
+	mult, ok := unitMultipliers[strings.ToLower(m[2])]
+	if !ok {
+		return 0, false, nil
+	}
+	n, err := strconv.ParseInt(m[1], 10, 64)
+	if err != nil {
+		return 0, true, err
+	}
+	if n > math.MaxInt64/mult || n < math.MinInt64/mult {
+		return 0, true, strconv.ErrRange
+	}
+	return n * mult, true, nil
+}
+
+// ParseConfFile reads and parses a NATS server conf file.
+func ParseConfFile(path string) (*Document, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConf(path, b)
+}
+
+// ParseConf parses the source of a NATS server conf file. The filename
+// is only used for positions.
+func ParseConf(filename string, src []byte) (*Document, error) {
+	p := confParser{
+		lx: newLexer(filename, src),
+	}
+
+	root, err := p.parseMap(true, p.lx.pos())
+	if err != nil {
+		return nil, err
+	}
+
+	return &Document{
+		Filename: filename,
+		Root:     root,
+	}, nil
+}
+
+type confParser struct {
+	lx *lexer
+}
+
+// parseMap parses the entries of a map. If root is true, this is the
+// top-level map and is terminated by the end of the file, otherwise
+// the opening brace is expected to be consumed and the closing brace
+// terminates it.
+func (p *confParser) parseMap(root bool, pos Pos) (*Value, error) {
+	lx := p.lx
+	m := &Value{
+		Kind: MapValue,
+		Pos:  pos,
+	}
+
+	for {
+		comments, blank := lx.blank(true)
+
+		switch c := lx.peek(); {
+		case c == eof:
+			if !root {
+				return nil, lx.errorf(m.Pos, "unterminated map")
+			}
+			m.TrailingComments = comments
+			m.End = lx.pos()
+			return m, nil
+
+		case c == '}':
+			if root {
+				return nil, lx.errorf(lx.pos(), "unexpected '}'")
+			}
+			lx.next()
+			m.TrailingComments = comments
+			m.End = lx.pos()
+			return m, nil
+		}
+
+		e, err := p.parseEntry()
+		if err != nil {
+			return nil, err
+		}
+		e.Comments = comments
+		e.BlankBefore = blank || (len(comments) > 0 && comments[0].BlankBefore)
+		m.Entries = append(m.Entries, e)
+
+		// An entry is terminated by a newline, a separator, or the end of
+		// the map. A comment may follow on the same line.
+		lx.skipSpace()
+		sep := false
+		if c := lx.peek(); c == ',' || c == ';' {
+			lx.next()
+			lx.skipSpace()
+			sep = true
+		}
+		if lx.atComment() {
+			e.LineComment = lx.comment()
+			continue
+		}
+		if c := lx.peek(); !sep && c != '\n' && c != '}' && c != eof {
+			return nil, lx.errorf(lx.pos(), "unexpected %s after value of %q, expected newline or separator", describeByte(c), e.Key)
+		}
+	}
+}
+
+// parseEntry parses a key-value pair or an include directive.
+func (p *confParser) parseEntry() (*Entry, error) {
+	lx := p.lx
+	e := &Entry{
+		KeyPos: lx.pos(),
+	}
+
+	switch c := lx.peek(); c {
+	case '"', '\'':
+		_, key, err := lx.quoted()
+		if err != nil {
+			return nil, err
+		}
+		e.Key = key
+		e.KeyQuote = byte(c)
+	default:
+		e.Key = lx.bareKey()
+		if e.Key == "" {
+			return nil, lx.errorf(lx.pos(), "unexpected %s, expected key", describeByte(c))
+		}
+	}
+
+	lx.skipSpace()
+
+	// The include directive is not followed by a separator.
+	if e.KeyQuote == 0 && strings.EqualFold(e.Key, "include") {
+		if c := lx.peek(); c != '=' && c != ':' {
+			v, err := p.parseIncludePath()
+			if err != nil {
+				return nil, err
+			}
+			e.Include = true
+			e.Value = v
+			return e, nil
+		}
+	}
+
+	if c := lx.peek(); c == '=' || c == ':' {
+		lx.next()
+		e.Sep = string(rune(c))
+		lx.skipSpace()
+	}
+
+	if c := lx.peek(); c == '\n' || c == eof || lx.atComment() {
+		return nil, lx.errorf(lx.pos(), "missing value for key %q", e.Key)
+	}
+
+	v, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	e.Value = v
+
+	return e, nil
+}
+
+func (p *confParser) parseIncludePath() (*Value, error) {
+	lx := p.lx
+	pos := lx.pos()
+
+	var v *Value
+	switch c := lx.peek(); c {
+	case '"', '\'':
+		raw, s, err := lx.quoted()
+		if err != nil {
+			return nil, err
+		}
+		v = &Value{Kind: StringValue, Raw: raw, Str: s, Quote: byte(c)}
+	default:
+		s := lx.bareValue()
+		if s == "" {
+			return nil, lx.errorf(pos, "missing include path")
+		}
+		v = &Value{Kind: StringValue, Raw: s, Str: s}
+	}
+	v.Pos = pos
+	v.End = lx.pos()
+	return v, nil
+}
+
+// parseValue parses a value of any kind.
+func (p *confParser) parseValue() (*Value, error) {
+	lx := p.lx
+	pos := lx.pos()
+
+	var (
+		v   *Value
+		err error
+	)
+
+	switch c := lx.peek(); {
+	case c == '{':
+		lx.next()
+		return p.parseMap(false, pos)
+
+	case c == '[':
+		lx.next()
+		return p.parseArray(pos)
+
+	case c == '"' || c == '\'':
+		raw, s, qerr := lx.quoted()
+		if qerr != nil {
+			return nil, qerr
+		}
+		v = &Value{Kind: StringValue, Raw: raw, Str: s, Quote: byte(c)}
+
+	case lx.atBlockString():
+		raw, s, berr := lx.blockString()
+		if berr != nil {
+			return nil, berr
+		}
+		v = &Value{Kind: StringValue, Raw: raw, Str: s, Quote: '('}
+
+	case c == '$' && isVariableChar(lx.peekAt(1)):
+		lx.next()
+		start := lx.off
+		for isVariableChar(lx.peek()) {
+			lx.next()
+		}
+		name := string(lx.src[start:lx.off])
+		if !isValueEnd(lx.peek()) {
+			return nil, lx.errorf(lx.pos(), "invalid character %s in variable reference", describeByte(lx.peek()))
+		}
+		v = &Value{Kind: VariableValue, Raw: "$" + name, Str: name}
+
+	case isValueEnd(c) || lx.atComment():
+		return nil, lx.errorf(pos, "missing value")
+
+	default:
+		v, err = parseBareValue(lx.bareValue())
+		if err != nil {
+			return nil, lx.errorf(pos, "%s", err)
+		}
+	}
+
+	v.Pos = pos
+	v.End = lx.pos()
+	return v, nil
+}
+
+// parseBareValue determines the kind of an unquoted value. Values which
+// are not a boolean, integer, or float are strings.
+func parseBareValue(s string) (*Value, error) {
+	v := &Value{Raw: s}
+
+	switch strings.ToLower(s) {
+	case "true", "yes", "on":
+		v.Kind = BoolValue
+		v.Bool = true
+		return v, nil
+	case "false", "no", "off":
+		v.Kind = BoolValue
+		return v, nil
+	}
+
+	n, ok, err := parseInteger(s)
+	if ok {
+		if err != nil {
+			return nil, fmt.Errorf("integer %q out of range", s)
+		}
+		v.Kind = IntegerValue
+		v.Int = n
+		return v, nil
+	}
+
+	if floatRe.MatchString(s) {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, err
+		}
+		v.Kind = FloatValue
+		v.Float = f
+		return v, nil
+	}
+
+	v.Kind = StringValue
+	v.Str = s
+	return v, nil
+}
+
+// parseArray parses the elements of an array. The opening bracket is
+// expected to be consumed.
+func (p *confParser) parseArray(pos Pos) (*Value, error) {
+	lx := p.lx
+	a := &Value{
+		Kind: ArrayValue,
+		Pos:  pos,
+	}
+
+	for {
+		comments, _ := lx.blank(false)
+
+		switch lx.peek() {
+		case eof:
+			return nil, lx.errorf(pos, "unterminated array")
+		case ']':
+			lx.next()
+			a.TrailingComments = comments
+			a.End = lx.pos()
+			return a, nil
+		}
+
+		v, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		v.Comments = comments
+		a.Elems = append(a.Elems, v)
+
+		lx.skipSpace()
+		sep := false
+		if lx.peek() == ',' {
+			lx.next()
+			lx.skipSpace()
+			sep = true
+		}
+		if lx.atComment() {
+			v.LineComment = lx.comment()
+			continue
+		}
+		if c := lx.peek(); !sep && c != '\n' && c != ']' && c != eof {
+			return nil, lx.errorf(lx.pos(), "unexpected %s in array, expected ',' or ']'", describeByte(c))
+		}
+	}
+}
+
+func describeByte(c int) string {
+	switch c {
+	case eof:
+		return "end of file"
+	case '\n':
+		return "newline"
+	}
+	return strconv.QuoteRune(rune(c))
+}
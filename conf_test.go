@@ -0,0 +1,156 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestParseConfValues(t *testing.T) {
+	tests := []struct {
+		src   string
+		kind  ValueKind
+		str   string
+		int   int64
+		float float64
+		bool  bool
+		quote byte
+	}{
+		{src: `v: 4222`, kind: IntegerValue, int: 4222},
+		{src: `v = -12`, kind: IntegerValue, int: -12},
+		{src: `v 1k`, kind: IntegerValue, int: 1000},
+		{src: `v: 1KB`, kind: IntegerValue, int: 1 << 10},
+		{src: `v: 64MB`, kind: IntegerValue, int: 64 << 20},
+		{src: `v: 2Gi`, kind: IntegerValue, int: 2 << 30},
+		{src: `v: 1tib`, kind: IntegerValue, int: 1 << 40},
+		{src: `v: 1.5`, kind: FloatValue, float: 1.5},
+		{src: `v: .5e1`, kind: FloatValue, float: 5},
+		{src: `v: true`, kind: BoolValue, bool: true},
+		{src: `v: Yes`, kind: BoolValue, bool: true},
+		{src: `v: off`, kind: BoolValue},
+		{src: `v: 2m30s`, kind: StringValue, str: "2m30s"},
+		{src: `v: 127.0.0.1`, kind: StringValue, str: "127.0.0.1"},
+		{src: `v: nats://localhost:4222`, kind: StringValue, str: "nats://localhost:4222"},
+		{src: `v: "a\tbé"`, kind: StringValue, str: "a\tbé", quote: '"'},
+		{src: `v: 'a\tb'`, kind: StringValue, str: `a\tb`, quote: '\''},
+		{src: `v: "true"`, kind: StringValue, str: "true", quote: '"'},
+		{src: "v: (\n  line one\n  line two\n)\n", kind: StringValue, str: "  line one\n  line two", quote: '('},
+		{src: `v: $PORT`, kind: VariableValue, str: "PORT"},
+	}
+
+	for _, tt := range tests {
+		doc, err := ParseConf("test.conf", []byte(tt.src))
+		if err != nil {
+			t.Errorf("ParseConf(%q): %v", tt.src, err)
+			continue
+		}
+		if n := len(doc.Root.Entries); n != 1 {
+			t.Errorf("ParseConf(%q): got %d entries, want 1", tt.src, n)
+			continue
+		}
+		v := doc.Root.Entries[0].Value
+		if v.Kind != tt.kind {
+			t.Errorf("ParseConf(%q): kind %s, want %s", tt.src, v.Kind, tt.kind)
+			continue
+		}
+		if v.Str != tt.str || v.Int != tt.int || v.Float != tt.float || v.Bool != tt.bool || v.Quote != tt.quote {
+			t.Errorf("ParseConf(%q) = {Str: %q, Int: %d, Float: %v, Bool: %v, Quote: %q}, want {Str: %q, Int: %d, Float: %v, Bool: %v, Quote: %q}",
+				tt.src, v.Str, v.Int, v.Float, v.Bool, v.Quote, tt.str, tt.int, tt.float, tt.bool, tt.quote)
+		}
+	}
+}
+
+func TestParseConfStructure(t *testing.T) {
+	const src = `# server
+port: 4222
+
+include "auth.conf"
+
+jetstream {
+  store_dir = "/data" // storage
+  max_mem: 1G; max_file: 10G
+}
+
+"quoted key": [1, two, {three: 3}
+  # four
+  4
+]
+`
+
+	doc, err := ParseConf("test.conf", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := doc.Root
+	if len(root.Entries) != 4 {
+		t.Fatalf("got %d entries, want 4", len(root.Entries))
+	}
+
+	port := root.Entries[0]
+	if port.Key != "port" || port.Sep != ":" || len(port.Comments) != 1 || port.Comments[0].Text != "# server" {
+		t.Errorf("port entry = %+v", port)
+	}
+
+	inc := root.Entries[1]
+	if !inc.Include || inc.Value.Str != "auth.conf" || !inc.BlankBefore {
+		t.Errorf("include entry = %+v", inc)
+	}
+
+	js := root.Entries[2]
+	if js.Value.Kind != MapValue || len(js.Value.Entries) != 3 {
+		t.Fatalf("jetstream = %+v", js.Value)
+	}
+	storeDir := js.Value.Entries[0]
+	if storeDir.Sep != "=" || storeDir.LineComment == nil || storeDir.LineComment.Text != "// storage" {
+		t.Errorf("store_dir entry = %+v", storeDir)
+	}
+	if maxFile := js.Value.Entries[2]; maxFile.Key != "max_file" || maxFile.Value.Int != 10*1000*1000*1000 {
+		t.Errorf("max_file entry = %+v", maxFile)
+	}
+	if pos := js.Value.Entries[1].KeyPos; pos.Line != 8 || pos.Column != 3 {
+		t.Errorf("max_mem at %s, want 8:3", pos)
+	}
+
+	arr := root.Entries[3]
+	if arr.Key != "quoted key" || arr.KeyQuote != '"' || arr.Value.Kind != ArrayValue {
+		t.Fatalf("quoted key entry = %+v", arr)
+	}
+	elems := arr.Value.Elems
+	if len(elems) != 4 {
+		t.Fatalf("got %d elements, want 4", len(elems))
+	}
+	if elems[1].Str != "two" || elems[2].Kind != MapValue || elems[3].Int != 4 {
+		t.Errorf("elements = %+v", elems)
+	}
+	if len(elems[3].Comments) != 1 || elems[3].Comments[0].Text != "# four" {
+		t.Errorf("comments of the last element = %+v", elems[3].Comments)
+	}
+}
+
+func TestParseConfErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{src: "port:\n", want: `test.conf:1:6: missing value for key "port"`},
+		{src: "a {\n  b: 1\n", want: "test.conf:1:3: unterminated map"},
+		{src: "a: [1, 2\n", want: "test.conf:1:4: unterminated array"},
+		{src: "a: [1 2]\n", want: `test.conf:1:7: unexpected '2' in array, expected ',' or ']'`},
+		{src: "}\n", want: "test.conf:1:1: unexpected '}'"},
+		{src: "a: \"foo\n", want: "test.conf:1:4: unterminated quoted string"},
+		{src: `a: "\q"`, want: `test.conf:1:6: invalid escape sequence \q`},
+		{src: "a: (\nfoo\n", want: "test.conf:1:4: unterminated block string"},
+		{src: "a: 1 b: 2\n", want: `test.conf:1:6: unexpected 'b' after value of "a", expected newline or separator`},
+		{src: "a: $FOO.bar\n", want: `test.conf:1:8: invalid character '.' in variable reference`},
+		{src: "a: 99999999999EB\n", want: `test.conf:1:4: integer "99999999999EB" out of range`},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseConf("test.conf", []byte(tt.src))
+		if err == nil {
+			t.Errorf("ParseConf(%q): no error, want %q", tt.src, tt.want)
+			continue
+		}
+		if err.Error() != tt.want {
+			t.Errorf("ParseConf(%q): error %q, want %q", tt.src, err, tt.want)
+		}
+	}
+}
@@ -39,133 +39,287 @@ func splitText(text string, length int) []string {
 	return lines
 }
 
-// prefixLines prepends a prefix to a slice of lines.
+// prefixLines prepends a prefix to a slice of lines. Trailing whitespace
+// is trimmed so empty lines remain empty.
 func prefixLines(prefix string, lines []string) []string {
 	for i, l := range lines {
-		lines[i] = fmt.Sprintf("%s%s", prefix, l)
+		lines[i] = strings.TrimRight(fmt.Sprintf("%s%s", prefix, l), " ")
 	}
 	return lines
 }
 
-func GenerateConfig(w io.Writer, sections []*Section) {
-	generateConfig(w, 0, 0, sections, false)
+// commentLines comments out a slice of lines.
+func commentLines(lines []string) []string {
+	return prefixLines("# ", lines)
 }
 
-func generateConfigValue(w io.Writer, depth int, cdepth int, p *Property, disabled bool) {
-	/*
-		tab := strings.Repeat(" ", depth*defaulTabSize)
-		//ctab := strings.Repeat(" ", cdepth*defaulTabSize)
-
-			first := true
-			for _, k := range p.Types {
-				var prefixChar byte
-				var suffixChar byte
-
-				if strings.HasPrefix(k, "[]") {
-					prefixChar = '['
-					suffixChar = ']'
-					k = k[2:]
-				} else if strings.HasPrefix(k, "{}") {
-					prefixChar = '{'
-					suffixChar = '}'
-					k = k[2:]
-				}
+// indentLines indents a slice of lines by one level.
+func indentLines(lines []string) []string {
+	return prefixLines(strings.Repeat(" ", defaulTabSize), lines)
+}
 
-				// Ignore unknown types which should only be primitives at this stage.
-				t, ok := types[k]
-				if !ok {
-					continue
-				}
+// GenerateConfig writes a reference conf file for the sections. Each property
+// is annotated with its description, examples, and deprecation notes. Values
+// are commented out and either show the default or a placeholder of the type,
+// while object blocks are present unless they are disabled.
+func GenerateConfig(w io.Writer, sections []*Section) error {
+	for _, l := range generateConfig(sections, false) {
+		if _, err := fmt.Fprintln(w, l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-				if first {
-					fmt.Fprintf(w, "%s# Forms:\n", tab)
-					first = false
-				}
+// generateConfig returns the lines for a set of sections. If disabled is true,
+// the lines are already within a commented out block.
+func generateConfig(sections []*Section, disabled bool) []string {
+	var lines []string
 
-				if len(t.Sections) > 0 {
-					fmt.Fprintf(w, "%s# %s%s %c{\n", tab, ctab, t.Name, prefixChar)
-					generateConfigValue(w, depth, cdepth+1, types, t)
-					fmt.Fprintf(w, "%s# %s}%c\n", tab, ctab, suffixChar)
-				} else {
-					generateConfigValue(w, depth, cdepth+1, types, t)
-				}
-			}
+	for _, s := range sections {
+		if len(lines) > 0 {
+			lines = append(lines, "")
+		}
+
+		if s.Name != "" {
+			line := strings.Repeat("#", len(s.Name)+4)
+			lines = append(lines, line, fmt.Sprintf("# %s #", s.Name), line, "")
+		}
 
-		if p.Default == nil {
-			var typs []string
-			for _, t := range p.Types {
-				typs = append(typs, t.Type)
+		for i, p := range s.Properties {
+			if i > 0 {
+				lines = append(lines, "")
 			}
-			t := strings.Join(typs, " | ")
-			fmt.Fprintf(w, "%s# %s: <%s>\n", tab, p.Name, t)
-		} else {
-			fmt.Fprintf(w, "%s# %s: %v\n", tab, p.Name, p.Default)
+			lines = append(lines, generateConfigValue(p, disabled)...)
 		}
-	*/
+	}
+
+	return lines
 }
 
-func generateConfig(w io.Writer, depth int, cdepth int, sections []*Section, disabled bool) {
-	/*
-		// Comment indent
-		tab := strings.Repeat(" ", depth*defaulTabSize)
-		// Code indent
-		//ctab := strings.Repeat(" ", cdepth*defaulTabSize)
+// generateConfigValue returns the lines for a property, starting with
+// the annotations followed by the value. If disabled is true, the lines
+// are already within a commented out block.
+func generateConfigValue(p *Property, disabled bool) []string {
+	var lines []string
+
+	comment := func(text string) {
+		lines = append(lines, commentLines(splitText(text, defaultLineLength))...)
+	}
+
+	if p.Description != "" {
+		comment(p.Description)
+	}
 
-		for j, s := range sections {
-			if j > 0 {
-				fmt.Fprintf(w, "\n")
+	if len(p.Examples) > 0 {
+		lines = append(lines, "# Examples:")
+		for _, e := range p.Examples {
+			value := strings.Split(strings.TrimSpace(e.Value), "\n")
+			switch {
+			case e.Label == "":
+				lines = append(lines, fmt.Sprintf("#   - %s", value[0]))
+				value = value[1:]
+			case len(value) == 1:
+				lines = append(lines, fmt.Sprintf("#   - %s: %s", e.Label, value[0]))
+				value = nil
+			default:
+				lines = append(lines, fmt.Sprintf("#   - %s:", e.Label))
 			}
+			lines = append(lines, prefixLines("#       ", value)...)
+		}
+	}
 
-			if s.Name != "" {
-				line := strings.Repeat("#", len(s.Name)+4)
-				fmt.Fprintf(w, "%s%s\n", tab, line)
-				fmt.Fprintf(w, "%s# %s #\n", tab, s.Name)
-				fmt.Fprintf(w, "%s%s\n\n", tab, line)
+	if p.Deprecation != "" {
+		lines = append(lines, "# Deprecation Note:")
+		comment(p.Deprecation)
+	}
+
+	if len(p.Types) == 0 {
+		return lines
+	}
+
+	// Multiple types are listed as the forms the value can take. Forms
+	// spanning multiple lines, such as objects, are rendered in full below
+	// as commented out alternatives.
+	if len(p.Types) > 1 {
+		lines = append(lines, "# Forms:")
+		var blocks []string
+		for _, o := range p.Types {
+			value := configProperty(p.Name, o, nil, true)
+			if len(value) == 1 {
+				lines = append(lines, fmt.Sprintf("#   %s", value[0]))
+				continue
 			}
+			lines = append(lines, fmt.Sprintf("#   %s ... %s", value[0], value[len(value)-1]))
+			blocks = append(blocks, commentLines(value)...)
+		}
+		if p.Default != nil {
+			lines = append(lines, commentLines(configProperty(p.Name, p.Types[0], p.Default, true))...)
+		}
+		return append(lines, blocks...)
+	}
 
-			lastIndex := len(s.Properties) - 1
-			for i, p := range s.Properties {
-				if p.Description != "" {
-					lines := splitText(p.Description, defaultLineLength)
-					for _, line := range lines {
-						fmt.Fprintf(w, "%s# %s\n", tab, line)
-					}
-				}
+	// Object blocks are present unless disabled while other values are
+	// always commented out, even within a disabled block, so the block
+	// can be uncommented as a whole.
+	o := p.Types[0]
+	leaf := o.Type != "object" || isContainer(o)
+	commented := leaf || (p.Disabled && !disabled)
+	value := configProperty(p.Name, o, p.Default, disabled || commented)
+	if commented {
+		value = commentLines(value)
+	}
 
-				if len(p.Examples) > 0 {
-					fmt.Fprintf(w, "%s# Examples:\n", tab)
-					for _, e := range p.Examples {
-						fmt.Fprintf(w, "%s#   - %v\n", tab, e)
-					}
-				}
+	return append(lines, value...)
+}
 
-				if len(p.Deprecation) > 0 {
-					fmt.Fprintf(w, "%s# Deprecation Note:\n", tab)
-					lines := splitText(p.Deprecation, defaultLineLength)
-					for _, line := range lines {
-						fmt.Fprintf(w, "%s# %s\n", tab, line)
-					}
-				}
+// configProperty returns the lines for a property having the type option.
+// Objects and maps are rendered as blocks, e.g. `tls {`, and other values
+// with a separator, e.g. `port: 4222`. If disabled is true, the lines are
+// to be commented out.
+func configProperty(name string, o *TypeOption, def any, disabled bool) []string {
+	value := configValue(o, def, disabled)
+	if strings.HasPrefix(value[0], "{") {
+		value[0] = fmt.Sprintf("%s %s", confKey(name), value[0])
+	} else {
+		value[0] = fmt.Sprintf("%s: %s", confKey(name), value[0])
+	}
+	return value
+}
 
-				if len(p.Sections) > 0 {
-					var prefixByte byte
-					if disabled || p.Disabled {
-						prefixByte = '#'
-					}
-					fmt.Fprintf(w, "%s%c%s {\n", tab, prefixByte, p.Name)
-					generateConfig(w, depth+1, cdepth, p.Sections, p.Disabled)
-					fmt.Fprintf(w, "%s%c}\n", tab, prefixByte)
-					if i != lastIndex {
-						fmt.Fprintf(w, "\n")
-					}
-					continue
-				}
+// configValue returns the lines for a value of the type option. If the
+// default is nil, a placeholder of the type is used, e.g. `<integer>`.
+func configValue(o *TypeOption, def any, disabled bool) []string {
+	if def != nil {
+		return []string{formatDefault(o, def)}
+	}
+
+	// Peel off the outermost container.
+	var container ValueKind
+	inner := *o
+	inner.Array, inner.Map, inner.MapOfArray, inner.ArrayOfMap, inner.MapOfMap, inner.ArrayOfArray = false, false, false, false, false, false
+	switch {
+	case o.Array:
+		container = ArrayValue
+	case o.ArrayOfMap:
+		container = ArrayValue
+		inner.Map = true
+	case o.ArrayOfArray:
+		container = ArrayValue
+		inner.Array = true
+	case o.Map:
+		container = MapValue
+	case o.MapOfArray:
+		container = MapValue
+		inner.Array = true
+	case o.MapOfMap:
+		container = MapValue
+		inner.Map = true
+	case o.Type == "object":
+		lines := []string{"{"}
+		lines = append(lines, indentLines(generateConfig(o.Sections, disabled))...)
+		return append(lines, "}")
+	default:
+		if len(o.Choices) > 0 && o.Type != "boolean" {
+			return []string{fmt.Sprintf("<%s>", strings.Join(o.Choices, " | "))}
+		}
+		return []string{fmt.Sprintf("<%s>", o.Type)}
+	}
+
+	if container == ArrayValue {
+		value := configValue(&inner, nil, disabled)
+		if len(value) == 1 {
+			return []string{fmt.Sprintf("[%s]", value[0])}
+		}
+		lines := []string{"["}
+		lines = append(lines, indentLines(value)...)
+		return append(lines, "]")
+	}
+
+	value := configProperty("<name>", &inner, nil, disabled)
+	if len(value) == 1 {
+		return []string{fmt.Sprintf("{%s}", value[0])}
+	}
+	lines := []string{"{"}
+	lines = append(lines, indentLines(value)...)
+	return append(lines, "}")
+}
 
-				generateConfigValue(w, depth, cdepth, p, disabled)
-				if i != lastIndex {
-					fmt.Fprintf(w, "\n")
+// formatDefault formats a default value from the YAML definition as a
+// conf value of the type option. Strings are written bare if they are read
+// back as a valid value of the type, e.g. `64K` for an integer or `1MB`
+// for a storage size, and quoted otherwise.
+func formatDefault(o *TypeOption, v any) string {
+	switch x := v.(type) {
+	case string:
+		if o != nil && o.Type != "string" && x != "" && strings.IndexFunc(x, func(r rune) bool { return r < 0x20 || isValueEnd(int(r)) }) < 0 {
+			if val, err := parseBareValue(x); err == nil {
+				if ok, msg := matchScalar(o, val); ok && msg == "" {
+					return x
 				}
 			}
 		}
-	*/
+		return confString(x)
+	case []any:
+		var elems []string
+		for _, e := range x {
+			elems = append(elems, formatDefault(o, e))
+		}
+		return fmt.Sprintf("[%s]", strings.Join(elems, ", "))
+	}
+	return fmt.Sprint(v)
+}
+
+// confKey returns the key quoted if it could not be parsed as a bare key.
+func confKey(s string) string {
+	if s == "" || strings.IndexFunc(s, func(r rune) bool { return r < 0x20 || isKeyEnd(int(r)) }) >= 0 {
+		return quoteConf(s)
+	}
+	return s
+}
+
+// confString returns the string as a conf value, quoting it unless it
+// would be parsed back as the same string when left bare. Strings starting
+// with a digit or a dot are always quoted to avoid them being mistaken for
+// numbers, such as `3secret`.
+func confString(s string) string {
+	if s == "" || strings.ContainsAny(s[:1], "0123456789.-+$('\"#{[") || strings.HasPrefix(s, "//") {
+		return quoteConf(s)
+	}
+	if strings.IndexFunc(s, func(r rune) bool { return r < 0x20 || isValueEnd(int(r)) }) >= 0 {
+		return quoteConf(s)
+	}
+	if v, err := parseBareValue(s); err != nil || v.Kind != StringValue {
+		return quoteConf(s)
+	}
+	return s
+}
+
+// quoteConf returns the string double-quoted using only the escape
+// sequences supported by the conf parser.
+func quoteConf(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(&b, `\u%04x`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
 }
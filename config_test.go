@@ -0,0 +1,72 @@
+package config
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestFormatDefault(t *testing.T) {
+	tests := []struct {
+		typ  string
+		def  any
+		want string
+	}{
+		{"integer", "64K", "64K"},
+		{"integer", 4222, "4222"},
+		{"storage", "1MB", "1MB"},
+		{"storage", "64MB", "64MB"},
+		{"duration", "10s", "10s"},
+		{"duration", "500ms", "500ms"},
+		// Unquoted, `2m` would be read as the number 2000000.
+		{"duration", "2m", `"2m"`},
+		{"string", "0.0.0.0", `"0.0.0.0"`},
+		{"string", "$SYS", `"$SYS"`},
+		{"string", "single", "single"},
+		{"boolean", false, "false"},
+		{"duration", []any{"10ms", "50ms"}, "[10ms, 50ms]"},
+	}
+
+	for _, tt := range tests {
+		got := formatDefault(&TypeOption{Type: tt.typ}, tt.def)
+		if got != tt.want {
+			t.Errorf("formatDefault(%s, %v) = %s, want %s", tt.typ, tt.def, got, tt.want)
+		}
+	}
+}
+
+// topLevelDefaultRe matches a commented top-level property set to its
+// default, rather than a placeholder such as `<integer>`.
+var topLevelDefaultRe = regexp.MustCompile(`^# ([a-z_]+: [^<].*)$`)
+
+func TestGenerateConfigDefaultsValidate(t *testing.T) {
+	cfg, err := DefaultConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := GenerateConfig(&b, cfg.Sections); err != nil {
+		t.Fatal(err)
+	}
+
+	// Uncomment the top-level defaults, which must be valid as is.
+	var lines []string
+	for _, l := range strings.Split(b.String(), "\n") {
+		if m := topLevelDefaultRe.FindStringSubmatch(l); m != nil {
+			lines = append(lines, m[1])
+		}
+	}
+	if len(lines) == 0 {
+		t.Fatal("no defaults found in the generated conf")
+	}
+
+	doc, err := ParseConf("reference.conf", []byte(strings.Join(lines, "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range ValidateDocument(cfg, doc) {
+		t.Errorf("%s", d)
+	}
+}
@@ -0,0 +1,465 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfToJSON converts a parsed conf document to JSON. If the config is not
+// nil, it is used to render values by their type, e.g. durations as strings
+// such as "30s" and storage sizes with units as strings such as "1GB".
+// Comments are dropped.
+func ConfToJSON(doc *Document, cfg *Config) ([]byte, error) {
+	n, err := confToNode(doc, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	if err := writeJSON(&b, n, ""); err != nil {
+		return nil, err
+	}
+	b.WriteByte('\n')
+	return b.Bytes(), nil
+}
+
+// ConfToYAML converts a parsed conf document to YAML, rendering values the
+// same way as ConfToJSON. Comments are preserved.
+func ConfToYAML(doc *Document, cfg *Config) ([]byte, error) {
+	n, err := confToNode(doc, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Comments separated from the first entry by a blank line are the
+	// header of the file, as read by YAMLToConf.
+	d := &yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{n}}
+	if entries := mapEntries(doc.Root); len(entries) > 0 && len(n.Content) > 0 {
+		comments := entries[0].Comments
+		for i := len(comments) - 1; i >= 0; i-- {
+			if comments[i].BlankAfter {
+				d.HeadComment = yamlComment(comments[:i+1])
+				n.Content[0].HeadComment = yamlComment(comments[i+1:])
+				break
+			}
+		}
+	}
+
+	var b bytes.Buffer
+	e := yaml.NewEncoder(&b)
+	e.SetIndent(2)
+	if err := e.Encode(d); err != nil {
+		return nil, err
+	}
+	if err := e.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// JSONToConf converts a JSON object to a conf file. If the config is not
+// nil, it is used to render values by their type, e.g. durations are
+// always quoted and storage sizes with units are left unquoted.
+func JSONToConf(filename string, data []byte, cfg *Config) ([]byte, error) {
+	// JSON is a subset of YAML and decoding it as YAML retains the order
+	// of the keys.
+	return YAMLToConf(filename, data, cfg)
+}
+
+// YAMLToConf converts a YAML mapping to a conf file, rendering values the
+// same way as JSONToConf. Comments are preserved.
+func YAMLToConf(filename string, data []byte, cfg *Config) ([]byte, error) {
+	var n yaml.Node
+	if err := yaml.Unmarshal(data, &n); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	root := &n
+	if root.Kind == yaml.DocumentNode {
+		if len(root.Content) == 0 {
+			return nil, nil
+		}
+		root = root.Content[0]
+	}
+	if root.Kind != yaml.MappingNode {
+		return nil, &ParseError{
+			Pos: Pos{Filename: filename, Line: root.Line, Column: root.Column},
+			Msg: "expected an object",
+		}
+	}
+
+	c := nodeConverter{filename: filename}
+	v, err := c.value(root, rootOptions(cfg))
+	if err != nil {
+		return nil, err
+	}
+	// Header comments are separated from the first entry.
+	header := append(c.comments(n.HeadComment), c.comments(root.HeadComment)...)
+	if len(header) > 0 && len(v.Entries) > 0 {
+		header[len(header)-1].BlankAfter = true
+		if cs := v.Entries[0].Comments; len(cs) > 0 {
+			cs[0].BlankBefore = true
+		}
+		v.Entries[0].Comments = append(header, v.Entries[0].Comments...)
+	} else {
+		v.TrailingComments = header
+	}
+	v.TrailingComments = append(v.TrailingComments, c.comments(root.FootComment)...)
+	v.TrailingComments = append(v.TrailingComments, c.comments(n.FootComment)...)
+
+	return FormatConf(&Document{Filename: filename, Root: v}, nil), nil
+}
+
+// rootOptions returns the options of the top-level object of the config.
+func rootOptions(cfg *Config) []*TypeOption {
+	if cfg == nil {
+		return nil
+	}
+	return []*TypeOption{{
+		Type:     "object",
+		Sections: cfg.Sections,
+	}}
+}
+
+// entryOptions returns the options of the value of a map entry given the
+// options of the map.
+func entryOptions(opts []*TypeOption, key string) []*TypeOption {
+	opts = derefOptions(opts)
+	if inner := unwrapOptions(opts, MapValue); len(inner) > 0 {
+		return inner
+	}
+	for _, o := range opts {
+		if o.Type == "object" && !isContainer(o) {
+			if p := propertyIndex(o.Sections)[strings.ToLower(key)]; p != nil {
+				return p.Types
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// hasScalarType reports whether one of the non-container options is of
+// the type.
+func hasScalarType(opts []*TypeOption, typ string) bool {
+	for _, o := range derefOptions(opts) {
+		if o.Type == typ && !isContainer(o) {
+			return true
+		}
+	}
+	return false
+}
+
+// confToNode converts a conf document to a YAML node.
+func confToNode(doc *Document, cfg *Config) (*yaml.Node, error) {
+	n, err := confValueNode(doc.Root, rootOptions(cfg))
+	if err != nil {
+		return nil, err
+	}
+	n.FootComment = yamlComment(doc.Root.TrailingComments)
+	return n, nil
+}
+
+// confValueNode converts a conf value to a YAML node.
+func confValueNode(v *Value, opts []*TypeOption) (*yaml.Node, error) {
+	switch v.Kind {
+	case MapValue:
+		n := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, e := range mapEntries(v) {
+			if e.Include {
+				return nil, &ParseError{Pos: e.KeyPos, Msg: "cannot convert unresolved include directive"}
+			}
+			val, err := confValueNode(e.Value, entryOptions(opts, e.Key))
+			if err != nil {
+				return nil, err
+			}
+			k := &yaml.Node{
+				Kind:        yaml.ScalarNode,
+				Tag:         "!!str",
+				Value:       e.Key,
+				HeadComment: yamlComment(e.Comments),
+				LineComment: yamlComment([]*Comment{e.LineComment}),
+			}
+			n.Content = append(n.Content, k, val)
+		}
+		return n, nil
+
+	case ArrayValue:
+		inner := unwrapOptions(derefOptions(opts), ArrayValue)
+		n := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, x := range v.Elems {
+			val, err := confValueNode(x, inner)
+			if err != nil {
+				return nil, err
+			}
+			val.HeadComment = yamlComment(x.Comments)
+			val.LineComment = yamlComment([]*Comment{x.LineComment})
+			n.Content = append(n.Content, val)
+		}
+		return n, nil
+
+	case IntegerValue:
+		// Durations are interpreted as seconds and storage sizes keep
+		// their units.
+		switch {
+		case hasScalarType(opts, "duration"):
+			return stringNode(fmt.Sprintf("%ds", v.Int)), nil
+		case hasScalarType(opts, "storage") && v.Raw != fmt.Sprint(v.Int):
+			return stringNode(v.Raw), nil
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: fmt.Sprint(v.Int)}, nil
+
+	case FloatValue:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!float", Value: strconv.FormatFloat(v.Float, 'g', -1, 64)}, nil
+
+	case BoolValue:
+		// Unquoted choices such as `enabled` may be parsed as a boolean.
+		if hasScalarType(opts, "string") && !hasScalarType(opts, "boolean") {
+			return stringNode(v.Raw), nil
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(v.Bool)}, nil
+
+	case VariableValue:
+		return stringNode("$" + v.Str), nil
+	}
+
+	n := stringNode(v.Str)
+	if strings.Contains(v.Str, "\n") {
+		n.Style = yaml.LiteralStyle
+	}
+	return n, nil
+}
+
+func stringNode(s string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: s}
+}
+
+// yamlComment joins conf comments as a YAML comment.
+func yamlComment(comments []*Comment) string {
+	var lines []string
+	for _, c := range comments {
+		if c == nil {
+			continue
+		}
+		text := strings.TrimPrefix(strings.TrimPrefix(c.Text, "#"), "//")
+		lines = append(lines, "#"+text)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// writeJSON writes the YAML node as indented JSON, retaining the order of
+// the keys.
+func writeJSON(b *bytes.Buffer, n *yaml.Node, indent string) error {
+	const tab = "  "
+
+	switch n.Kind {
+	case yaml.MappingNode:
+		if len(n.Content) == 0 {
+			b.WriteString("{}")
+			return nil
+		}
+		b.WriteString("{\n")
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			b.WriteString(indent + tab)
+			writeJSONString(b, n.Content[i].Value)
+			b.WriteString(": ")
+			if err := writeJSON(b, n.Content[i+1], indent+tab); err != nil {
+				return err
+			}
+			if i+2 < len(n.Content) {
+				b.WriteByte(',')
+			}
+			b.WriteByte('\n')
+		}
+		b.WriteString(indent + "}")
+
+	case yaml.SequenceNode:
+		if len(n.Content) == 0 {
+			b.WriteString("[]")
+			return nil
+		}
+		b.WriteString("[\n")
+		for i, x := range n.Content {
+			b.WriteString(indent + tab)
+			if err := writeJSON(b, x, indent+tab); err != nil {
+				return err
+			}
+			if i+1 < len(n.Content) {
+				b.WriteByte(',')
+			}
+			b.WriteByte('\n')
+		}
+		b.WriteString(indent + "]")
+
+	case yaml.ScalarNode:
+		switch n.Tag {
+		case "!!int", "!!float", "!!bool":
+			b.WriteString(n.Value)
+		default:
+			writeJSONString(b, n.Value)
+		}
+
+	default:
+		return fmt.Errorf("unsupported YAML node kind %d", n.Kind)
+	}
+
+	return nil
+}
+
+// writeJSONString writes a JSON string without escaping HTML characters.
+func writeJSONString(b *bytes.Buffer, s string) {
+	e := json.NewEncoder(b)
+	e.SetEscapeHTML(false)
+	e.Encode(s)
+	b.Truncate(b.Len() - 1)
+}
+
+// nodeConverter converts YAML nodes to conf values.
+type nodeConverter struct {
+	filename string
+}
+
+func (c *nodeConverter) errorf(n *yaml.Node, format string, args ...any) error {
+	return &ParseError{
+		Pos: Pos{Filename: c.filename, Line: n.Line, Column: n.Column},
+		Msg: fmt.Sprintf(format, args...),
+	}
+}
+
+// comments converts a YAML comment to conf comments.
+func (c *nodeConverter) comments(s string) []*Comment {
+	if s == "" {
+		return nil
+	}
+	var comments []*Comment
+	for _, l := range strings.Split(s, "\n") {
+		l = strings.TrimSpace(l)
+		if l == "" {
+			continue
+		}
+		if !strings.HasPrefix(l, "#") {
+			l = "# " + l
+		}
+		comments = append(comments, &Comment{Text: l})
+	}
+	return comments
+}
+
+func (c *nodeConverter) lineComment(s string) *Comment {
+	if cs := c.comments(s); len(cs) > 0 {
+		return cs[0]
+	}
+	return nil
+}
+
+// value converts a YAML node to a conf value having the options.
+func (c *nodeConverter) value(n *yaml.Node, opts []*TypeOption) (*Value, error) {
+	if n.Kind == yaml.AliasNode {
+		n = n.Alias
+	}
+
+	switch n.Kind {
+	case yaml.MappingNode:
+		v := &Value{Kind: MapValue}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, vn := n.Content[i], n.Content[i+1]
+			if k.Kind != yaml.ScalarNode {
+				return nil, c.errorf(k, "expected a string key")
+			}
+			x, err := c.value(vn, entryOptions(opts, k.Value))
+			if err != nil {
+				return nil, err
+			}
+			v.Entries = append(v.Entries, &Entry{
+				Key:         k.Value,
+				Value:       x,
+				Comments:    c.comments(k.HeadComment),
+				LineComment: c.lineComment(k.LineComment + vn.LineComment),
+			})
+		}
+		return v, nil
+
+	case yaml.SequenceNode:
+		inner := unwrapOptions(derefOptions(opts), ArrayValue)
+		v := &Value{Kind: ArrayValue}
+		for _, en := range n.Content {
+			x, err := c.value(en, inner)
+			if err != nil {
+				return nil, err
+			}
+			x.Comments = c.comments(en.HeadComment)
+			x.LineComment = c.lineComment(en.LineComment)
+			v.Elems = append(v.Elems, x)
+		}
+		return v, nil
+
+	case yaml.ScalarNode:
+		return c.scalar(n, opts)
+	}
+
+	return nil, c.errorf(n, "unsupported value")
+}
+
+// scalar converts a YAML scalar to a conf value having the options.
+func (c *nodeConverter) scalar(n *yaml.Node, opts []*TypeOption) (*Value, error) {
+	switch n.ShortTag() {
+	case "!!null":
+		return nil, c.errorf(n, "null values are not supported")
+
+	case "!!bool":
+		var b bool
+		if err := n.Decode(&b); err != nil {
+			return nil, c.errorf(n, "%s", err)
+		}
+		return &Value{Kind: BoolValue, Raw: strconv.FormatBool(b), Bool: b}, nil
+
+	case "!!int":
+		var i int64
+		if err := n.Decode(&i); err != nil {
+			return nil, c.errorf(n, "%s", err)
+		}
+		// Durations are always written with units.
+		if hasScalarType(opts, "duration") && !hasScalarType(opts, "integer") {
+			return &Value{Kind: StringValue, Str: fmt.Sprintf("%ds", i), Quote: '"'}, nil
+		}
+		return &Value{Kind: IntegerValue, Raw: fmt.Sprint(i), Int: i}, nil
+
+	case "!!float":
+		var f float64
+		if err := n.Decode(&f); err != nil {
+			return nil, c.errorf(n, "%s", err)
+		}
+		raw := strconv.FormatFloat(f, 'f', -1, 64)
+		if !strings.Contains(raw, ".") {
+			raw += ".0"
+		}
+		return &Value{Kind: FloatValue, Raw: raw, Float: f}, nil
+	}
+
+	s := n.Value
+
+	// Variable references, e.g. `$NAME`.
+	if len(s) > 1 && s[0] == '$' && strings.IndexFunc(s[1:], func(r rune) bool { return !isVariableChar(int(r)) }) < 0 {
+		return &Value{Kind: VariableValue, Raw: s, Str: s[1:]}, nil
+	}
+
+	// Storage sizes with units are idiomatically unquoted, e.g. `1GB`.
+	if hasScalarType(opts, "storage") {
+		if i, ok, err := parseInteger(s); ok && err == nil {
+			return &Value{Kind: IntegerValue, Raw: s, Int: i}, nil
+		}
+	}
+
+	// Durations are always quoted, otherwise `1m` would be parsed as
+	// the number 1000000.
+	v := &Value{Kind: StringValue, Str: s}
+	if hasScalarType(opts, "duration") {
+		v.Quote = '"'
+	}
+	return v, nil
+}
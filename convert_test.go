@@ -0,0 +1,127 @@
+package config
+
+import (
+	"bytes"
+	"testing"
+)
+
+const convertSrc = `port: 4222
+write_deadline: "10s"
+ping_interval: 120
+max_payload: 1MB
+jetstream {
+    max_mem: 1G
+    store_dir: "/data"
+}
+cluster {
+    routes: ["nats://a:6222", "nats://b:6222"]
+}
+authorization {
+    users: [
+        {
+            user: a
+            password: $PASS
+        }
+    ]
+}
+PASS: "3secret"
+text: "line\nline"
+ratio: 0.5
+`
+
+func TestConvertRoundTrip(t *testing.T) {
+	cfg, err := DefaultConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Durations given as seconds are written with units, otherwise the
+	// conf is unchanged apart from quoting.
+	const want = `port: 4222
+write_deadline: "10s"
+ping_interval: "120s"
+max_payload: 1MB
+jetstream {
+    max_mem: 1G
+    store_dir: /data
+}
+cluster {
+    routes: [nats://a:6222, nats://b:6222]
+}
+authorization {
+    users: [
+        {
+            user: a
+            password: $PASS
+        }
+    ]
+}
+PASS: "3secret"
+text: "line\nline"
+ratio: 0.5
+`
+
+	for _, c := range []*Config{cfg, nil} {
+		doc, err := ParseConf("test.conf", []byte(convertSrc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		j, err := ConfToJSON(doc, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conf, err := JSONToConf("test.json", j, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c != nil && string(conf) != want {
+			t.Errorf("JSONToConf() =\n%s\nwant\n%s", conf, want)
+		}
+
+		// Converting the result again must give the same JSON.
+		doc, err = ParseConf("test.conf", conf)
+		if err != nil {
+			t.Fatalf("converted conf does not parse: %v\n%s", err, conf)
+		}
+		j2, err := ConfToJSON(doc, c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(j, j2) {
+			t.Errorf("schema %v: JSON changed after a round trip\nbefore:\n%s\nafter:\n%s", c != nil, j, j2)
+		}
+	}
+}
+
+func TestConvertYAMLComments(t *testing.T) {
+	cfg, err := DefaultConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const src = `# header
+
+# client port
+port: 4222 # default
+jetstream {
+    # memory
+    max_mem: 1G
+}
+`
+
+	doc, err := ParseConf("test.conf", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	y, err := ConfToYAML(doc, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf, err := YAMLToConf("test.yaml", y, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(conf) != src {
+		t.Errorf("YAMLToConf() =\n%s\nwant\n%s\nYAML:\n%s", conf, src, y)
+	}
+}
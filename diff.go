@@ -0,0 +1,287 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ChangeKind is the kind of change of a property between two configs.
+type ChangeKind int
+
+const (
+	PropertyAdded ChangeKind = iota
+	PropertyRemoved
+	PropertyChanged
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case PropertyAdded:
+		return "added"
+	case PropertyRemoved:
+		return "removed"
+	case PropertyChanged:
+		return "changed"
+	}
+	return "unknown"
+}
+
+// Change is a property that was added, removed, or changed between two
+// configs.
+type Change struct {
+	Kind ChangeKind
+
+	// Path is the dotted path of the property, e.g.
+	// `jetstream.max_memory_store`. Map values are denoted by `*`, e.g.
+	// `accounts.*.users`.
+	Path string
+
+	// Property is the new property or, if it was removed, the old property.
+	Property *Property
+
+	// Details describe how a changed property differs, e.g.
+	// "default changed from `4222` to `4223`".
+	Details []string
+}
+
+// DiffConfigs compares two configs, e.g. parsed from two revisions of the
+// schema, and returns the changes to the properties ordered by path.
+func DiffConfigs(old, new *Config) []*Change {
+	oldProps := make(map[string]*Property)
+	flattenProperties("", old.Sections, oldProps)
+	newProps := make(map[string]*Property)
+	flattenProperties("", new.Sections, newProps)
+
+	var changes []*Change
+	for path, p := range newProps {
+		op, ok := oldProps[path]
+		if !ok {
+			changes = append(changes, &Change{
+				Kind:     PropertyAdded,
+				Path:     path,
+				Property: p,
+			})
+			continue
+		}
+		if details := diffProperty(op, p); len(details) > 0 {
+			changes = append(changes, &Change{
+				Kind:     PropertyChanged,
+				Path:     path,
+				Property: p,
+				Details:  details,
+			})
+		}
+	}
+	for path, p := range oldProps {
+		if _, ok := newProps[path]; !ok {
+			changes = append(changes, &Change{
+				Kind:     PropertyRemoved,
+				Path:     path,
+				Property: p,
+			})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+
+	return changes
+}
+
+// flattenProperties indexes the properties of the sections, including
+// nested properties of objects, by their dotted path.
+func flattenProperties(prefix string, sections []*Section, props map[string]*Property) {
+	for _, s := range sections {
+		for _, p := range s.Properties {
+			path := joinPath(prefix, p.Name)
+			if _, ok := props[path]; ok {
+				continue
+			}
+			props[path] = p
+
+			for _, o := range p.Types {
+				// References to recursive types are not expanded.
+				if o.Ref != "" {
+					continue
+				}
+				opath := path
+				if o.Map || o.MapOfArray || o.ArrayOfMap || o.MapOfMap {
+					opath = joinPath(path, "*")
+				}
+				flattenProperties(opath, o.Sections, props)
+			}
+		}
+	}
+}
+
+// diffProperty returns the details of how the property changed.
+func diffProperty(old, new *Property) []string {
+	var details []string
+
+	oldTypes, newTypes := typeNames(old), typeNames(new)
+	if !reflect.DeepEqual(oldTypes, newTypes) {
+		details = append(details, fmt.Sprintf("type changed from %s to %s", codeList(oldTypes), codeList(newTypes)))
+	}
+
+	added, removed := diffStrings(choices(old), choices(new))
+	if len(added) > 0 {
+		details = append(details, fmt.Sprintf("choices added: %s", codeList(added)))
+	}
+	if len(removed) > 0 {
+		details = append(details, fmt.Sprintf("choices removed: %s", codeList(removed)))
+	}
+
+	if !reflect.DeepEqual(old.Default, new.Default) {
+		switch {
+		case old.Default == nil:
+			details = append(details, fmt.Sprintf("default set to `%s`", formatDefault(new.Types[0], new.Default)))
+		case new.Default == nil:
+			details = append(details, fmt.Sprintf("default `%s` removed", formatDefault(old.Types[0], old.Default)))
+		default:
+			details = append(details, fmt.Sprintf("default changed from `%s` to `%s`", formatDefault(old.Types[0], old.Default), formatDefault(new.Types[0], new.Default)))
+		}
+	}
+
+	if old.Reloadable != new.Reloadable {
+		if new.Reloadable {
+			details = append(details, "now reloadable")
+		} else {
+			details = append(details, "no longer reloadable")
+		}
+	}
+
+	added, removed = diffStrings(old.Aliases, new.Aliases)
+	if len(added) > 0 {
+		details = append(details, fmt.Sprintf("aliases added: %s", codeList(added)))
+	}
+	if len(removed) > 0 {
+		details = append(details, fmt.Sprintf("aliases removed: %s", codeList(removed)))
+	}
+
+	switch {
+	case old.Deprecation == new.Deprecation:
+	case old.Deprecation == "":
+		details = append(details, fmt.Sprintf("deprecated: %s", oneLine(new.Deprecation)))
+	case new.Deprecation == "":
+		details = append(details, "no longer deprecated")
+	default:
+		details = append(details, fmt.Sprintf("deprecation changed: %s", oneLine(new.Deprecation)))
+	}
+
+	return details
+}
+
+// typeNames returns the names of the type options, e.g. `array(string)`,
+// without the choices which are compared separately.
+func typeNames(p *Property) []string {
+	var names []string
+	for _, o := range p.Types {
+		x := *o
+		x.Choices = nil
+		names = append(names, describeOption(&x))
+	}
+	return names
+}
+
+// choices returns the choices of the type options, excluding booleans.
+func choices(p *Property) []string {
+	var cs []string
+	for _, o := range p.Types {
+		if o.Type != "boolean" {
+			cs = append(cs, o.Choices...)
+		}
+	}
+	return cs
+}
+
+// diffStrings returns the strings added to and removed from a list.
+func diffStrings(old, new []string) ([]string, []string) {
+	var added, removed []string
+	for _, s := range new {
+		if !containsString(old, s) {
+			added = append(added, s)
+		}
+	}
+	for _, s := range old {
+		if !containsString(new, s) {
+			removed = append(removed, s)
+		}
+	}
+	return added, removed
+}
+
+func containsString(l []string, s string) bool {
+	for _, x := range l {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// codeList formats the strings as a comma-separated list of code spans.
+func codeList(l []string) string {
+	if len(l) == 0 {
+		return "none"
+	}
+	cs := make([]string, len(l))
+	for i, s := range l {
+		cs[i] = fmt.Sprintf("`%s`", s)
+	}
+	return strings.Join(cs, ", ")
+}
+
+// GenerateChangelog writes the changes as a Markdown changelog grouped by
+// the kind of change.
+func GenerateChangelog(w io.Writer, changes []*Change) error {
+	o := func(str string, args ...any) {
+		fmt.Fprintf(w, str, args...)
+	}
+
+	groups := []struct {
+		kind  ChangeKind
+		title string
+	}{
+		{PropertyAdded, "Added"},
+		{PropertyRemoved, "Removed"},
+		{PropertyChanged, "Changed"},
+	}
+
+	var n int
+	for _, g := range groups {
+		var title bool
+		for _, c := range changes {
+			if c.Kind != g.kind {
+				continue
+			}
+			if !title {
+				if n > 0 {
+					o("\n")
+				}
+				o("## %s\n\n", g.title)
+				title = true
+			}
+			n++
+
+			switch c.Kind {
+			case PropertyChanged:
+				o("- `%s`\n", c.Path)
+				for _, d := range c.Details {
+					o("  - %s\n", d)
+				}
+			default:
+				o("- `%s` (%s)\n", c.Path, codeList(typeNames(c.Property)))
+			}
+		}
+	}
+
+	if n == 0 {
+		o("No changes.\n")
+	}
+
+	return nil
+}
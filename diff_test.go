@@ -0,0 +1,89 @@
+package config
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDiffConfigs(t *testing.T) {
+	const oldYAML = `
+sections:
+  - name: Server
+    properties:
+      port:
+        type: integer
+        default: 4222
+      mode:
+        type: string
+        choices: [a, b]
+      old:
+        type: string
+      jetstream:
+        type: object
+        sections:
+          - properties:
+              max_mem:
+                type: storage
+`
+	const newYAML = `
+sections:
+  - name: Server
+    properties:
+      port:
+        type: integer
+        default: 4223
+        aliases: [client_port]
+      mode:
+        type: string
+        choices: [a, c]
+        deprecation: Use other.
+      jetstream:
+        type: object
+        sections:
+          - properties:
+              max_mem:
+                types: [storage, array(storage)]
+              max_file:
+                type: storage
+`
+
+	old, err := parseTestConfig(oldYAML, "types: {}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	new, err := parseTestConfig(newYAML, "types: {}\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := GenerateChangelog(&b, DiffConfigs(old, new)); err != nil {
+		t.Fatal(err)
+	}
+
+	const want = "## Added\n\n" +
+		"- `jetstream.max_file` (`storage`)\n" +
+		"\n## Removed\n\n" +
+		"- `old` (`string`)\n" +
+		"\n## Changed\n\n" +
+		"- `jetstream.max_mem`\n" +
+		"  - type changed from `storage` to `storage`, `array(storage)`\n" +
+		"- `mode`\n" +
+		"  - choices added: `c`\n" +
+		"  - choices removed: `b`\n" +
+		"  - deprecated: Use other.\n" +
+		"- `port`\n" +
+		"  - default changed from `4222` to `4223`\n" +
+		"  - aliases added: `client_port`\n"
+	if b.String() != want {
+		t.Errorf("GenerateChangelog() =\n%s\nwant\n%s", b.String(), want)
+	}
+
+	b.Reset()
+	if err := GenerateChangelog(&b, DiffConfigs(old, old)); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "No changes.\n" {
+		t.Errorf("GenerateChangelog() without changes = %q", b.String())
+	}
+}
@@ -0,0 +1,347 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// CompletionItem is a property name or value suggested at a position in a
+// conf file.
+type CompletionItem struct {
+	// Label is the text to insert, i.e. the property name or the value.
+	Label string
+
+	// Detail describes the types of a property, e.g. `boolean or object`.
+	Detail string
+
+	// Property is the suggested property or the property of the value.
+	Property *Property
+
+	// Value is true if the item is a value rather than a property name.
+	Value bool
+}
+
+// Complete returns the property names or values that can be written at
+// the byte offset in the source of a conf file, which is typically being
+// edited and incomplete. At the start of a line within an object, the
+// properties of the object are suggested. Following a key, the choices of
+// the property, such as `enabled` or `disabled`, are suggested. Items are
+// filtered by the text preceding the offset.
+func Complete(cfg *Config, src []byte, offset int) []*CompletionItem {
+	if offset > len(src) {
+		offset = len(src)
+	}
+	cc, ok := scanCompletion(src[:offset])
+	if !ok {
+		return nil
+	}
+
+	opts := rootOptions(cfg)
+	for _, f := range cc.frames[1:] {
+		if f.key != "" {
+			opts = entryOptions(opts, f.key)
+		}
+		if f.array {
+			opts = unwrapOptions(derefOptions(opts), ArrayValue)
+		}
+	}
+
+	var items []*CompletionItem
+	prefix := strings.ToLower(cc.prefix)
+
+	if !cc.value {
+		idx := objectIndex(derefOptions(opts))
+		for _, o := range derefOptions(opts) {
+			if o.Type != "object" || isContainer(o) || idx == nil {
+				continue
+			}
+			for _, s := range o.Sections {
+				for _, p := range s.Properties {
+					if !strings.HasPrefix(strings.ToLower(p.Name), prefix) {
+						continue
+					}
+					items = append(items, &CompletionItem{
+						Label:    p.Name,
+						Detail:   describeOptions(p.Types),
+						Property: p,
+					})
+				}
+			}
+			break
+		}
+		return items
+	}
+
+	var p *Property
+	if cc.key != "" {
+		p = objectIndex(derefOptions(opts))[strings.ToLower(cc.key)]
+		opts = entryOptions(opts, cc.key)
+	}
+
+	seen := make(map[string]bool)
+	for _, o := range derefOptions(opts) {
+		if isContainer(o) {
+			continue
+		}
+		for _, c := range o.Choices {
+			if seen[c] || !strings.HasPrefix(strings.ToLower(c), prefix) {
+				continue
+			}
+			seen[c] = true
+			items = append(items, &CompletionItem{
+				Label:    c,
+				Detail:   describeOption(o),
+				Property: p,
+				Value:    true,
+			})
+		}
+	}
+	return items
+}
+
+// completionFrame is a map or array enclosing the completion position.
+type completionFrame struct {
+	// key is the key the map or array is the value of. It is empty for
+	// the root and the elements of an array.
+	key string
+
+	array bool
+}
+
+// completionContext describes the completion position.
+type completionContext struct {
+	// frames are the enclosing maps and arrays, starting with the root.
+	frames []completionFrame
+
+	// value is true if a value rather than a key is being written.
+	value bool
+
+	// key is the key of the value being written, if any.
+	key string
+
+	// prefix is the text of the key or value written so far.
+	prefix string
+}
+
+// scanCompletion scans the source preceding the completion position,
+// tracking the enclosing maps and arrays and whether a key or value is
+// being written. Unlike the parser, it tolerates incomplete input. The
+// boolean is false if the position is within a comment.
+func scanCompletion(src []byte) (completionContext, bool) {
+	const (
+		expectKey = iota
+		inKey
+		afterKey
+		inValue
+	)
+
+	frames := []completionFrame{{}}
+	state := expectKey
+	start := 0
+	var key string
+
+	top := func() completionFrame {
+		return frames[len(frames)-1]
+	}
+
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+
+		// The key ends at whitespace or a separator.
+		if state == inKey && (c == ' ' || c == '\t' || c == ':' || c == '=' || c == '\n' || c == '{' || c == '[') {
+			key = string(src[start:i])
+			state = afterKey
+		}
+
+		switch {
+		case c == '#' || (c == '/' && i+1 < len(src) && src[i+1] == '/'):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			if i == len(src) {
+				return completionContext{}, false
+			}
+			i--
+
+		case c == '\n' || c == ',' || c == ';':
+			if !top().array {
+				state = expectKey
+			}
+
+		case c == ' ' || c == '\t' || c == '\r':
+
+		case (c == ':' || c == '=') && state == afterKey:
+
+		case c == '{' || c == '[':
+			f := completionFrame{array: c == '['}
+			if !top().array && state == afterKey {
+				f.key = key
+			}
+			frames = append(frames, f)
+			state = expectKey
+
+		case c == '}' || c == ']':
+			if len(frames) > 1 {
+				frames = frames[:len(frames)-1]
+			}
+			state = inValue
+
+		case c == '"' || c == '\'':
+			if state == expectKey {
+				state = inKey
+			} else {
+				state = inValue
+			}
+			start = i + 1
+			for i++; i < len(src) && src[i] != c; i++ {
+				if c == '"' && src[i] == '\\' {
+					i++
+				}
+			}
+			if i >= len(src) {
+				return completionContext{frames: frames, value: state == inValue || top().array, key: key, prefix: string(src[start:])}, true
+			}
+			if state == inKey {
+				key = string(src[start:i])
+				state = afterKey
+			}
+
+		default:
+			switch {
+			case top().array:
+				if state != inValue {
+					start = i
+				}
+				state = inValue
+			case state == expectKey:
+				state = inKey
+				start = i
+			case state == afterKey:
+				state = inValue
+				start = i
+			}
+		}
+
+		// Whitespace ends a value within an array.
+		if top().array && (c == ' ' || c == '\t' || c == '\n' || c == ',') {
+			state = expectKey
+		}
+	}
+
+	cc := completionContext{frames: frames}
+	switch {
+	case top().array:
+		cc.value = true
+		if state == inValue {
+			cc.prefix = string(src[start:])
+		}
+	case state == expectKey:
+	case state == inKey:
+		cc.prefix = string(src[start:])
+	case state == afterKey:
+		cc.value = true
+		cc.key = key
+	case state == inValue:
+		cc.value = true
+		cc.key = key
+		cc.prefix = string(src[start:])
+	}
+	return cc, true
+}
+
+// Node is the key or value at a position in a conf file.
+type Node struct {
+	// Entry is the entry of the key or value.
+	Entry *Entry
+
+	// Value is the value at the position, or nil if it is the key.
+	Value *Value
+
+	// Property is the property of the entry, or nil if it is unknown.
+	Property *Property
+}
+
+// NodeAt returns the innermost key or value at the byte offset in the
+// document, along with its property. Only the entries of the document
+// itself are searched, not those of included files.
+func NodeAt(cfg *Config, doc *Document, offset int) *Node {
+	return nodeAtMap(doc.Root, rootOptions(cfg), offset)
+}
+
+func nodeAtMap(m *Value, opts []*TypeOption, offset int) *Node {
+	idx := objectIndex(derefOptions(opts))
+	for _, e := range m.Entries {
+		n := &Node{
+			Entry:    e,
+			Property: idx[strings.ToLower(e.Key)],
+		}
+
+		keyLen := len(e.Key)
+		if e.KeyQuote != 0 {
+			keyLen += 2
+		}
+		if offset >= e.KeyPos.Offset && offset < e.KeyPos.Offset+keyLen {
+			return n
+		}
+
+		if offset >= e.Value.Pos.Offset && offset < e.Value.End.Offset {
+			if e.Include {
+				n.Value = e.Value
+				return n
+			}
+			if x := nodeAtValue(n, e.Value, entryOptions(opts, e.Key), offset); x != nil {
+				return x
+			}
+		}
+	}
+	return nil
+}
+
+func nodeAtValue(n *Node, v *Value, opts []*TypeOption, offset int) *Node {
+	switch v.Kind {
+	case MapValue:
+		if x := nodeAtMap(v, opts, offset); x != nil {
+			return x
+		}
+	case ArrayValue:
+		inner := unwrapOptions(derefOptions(opts), ArrayValue)
+		for _, x := range v.Elems {
+			if offset >= x.Pos.Offset && offset < x.End.Offset {
+				return nodeAtValue(n, x, inner, offset)
+			}
+		}
+	}
+
+	return &Node{
+		Entry:    n.Entry,
+		Value:    v,
+		Property: n.Property,
+	}
+}
+
+// Definition returns the position a variable reference or an include
+// directive at the byte offset refers to, i.e. the key defining the
+// variable or the start of the included file. The variables and includes
+// of the document must have been resolved. The boolean is false if there
+// is nothing to go to, e.g. for environment variables.
+func Definition(doc *Document, offset int) (Pos, bool) {
+	n := NodeAt(nil, doc, offset)
+	if n == nil || n.Value == nil {
+		return Pos{}, false
+	}
+
+	if n.Entry.Include && n.Value == n.Entry.Value {
+		path := n.Value.Str
+		if n.Entry.Included != nil {
+			path = n.Entry.Included.Filename
+		} else if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(doc.Filename), path)
+		}
+		return Pos{Filename: path, Line: 1, Column: 1}, true
+	}
+
+	if n.Value.Kind == VariableValue && n.Value.Def != nil {
+		return n.Value.Def.KeyPos, true
+	}
+	return Pos{}, false
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// cursor returns the source without the `|` marking the cursor and the
+// offset of the cursor.
+func cursor(s string) ([]byte, int) {
+	offset := strings.Index(s, "|")
+	return []byte(strings.Replace(s, "|", "", 1)), offset
+}
+
+func TestComplete(t *testing.T) {
+	cfg, err := DefaultConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"jetstream {\n  max_m|\n}", "max_memory_store"},
+		{"jetstream {\n  cipher: |\n}", "chacha chachapoly aes"},
+		{"jetstream {\n  cipher: ch|\n}", "chacha chachapoly"},
+		{"jetstream: |", "true false enabled enable disabled disable"},
+		{"resolver {type: |}", "full cache"},
+		{"accounts {A {users: [{user: a, perm|}]}}", "permissions"},
+		{"wri|", "write_deadline"},
+		{"# por|", ""},
+	}
+
+	for _, tt := range tests {
+		src, offset := cursor(tt.src)
+		var labels []string
+		for _, it := range Complete(cfg, src, offset) {
+			labels = append(labels, it.Label)
+		}
+		if got := strings.Join(labels, " "); got != tt.want {
+			t.Errorf("Complete(%q) = %s, want %s", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestNodeAt(t *testing.T) {
+	cfg, err := DefaultConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		src   string
+		path  string
+		value string
+	}{
+		{"po|rt: 4222", "port", ""},
+		{"port: 42|22", "port", "4222"},
+		{"jetstream {max_m|em: 1G}", "max_memory_store", ""},
+		{"jetstream {max_mem: 1|G}", "max_memory_store", "1G"},
+		{"cluster {routes: [a, |b]}", "routes", "b"},
+		{"unknown: |1", "", "1"},
+	}
+
+	for _, tt := range tests {
+		src, offset := cursor(tt.src)
+		doc, err := ParseConf("test.conf", src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := NodeAt(cfg, doc, offset)
+		if n == nil {
+			t.Errorf("NodeAt(%q) = nil", tt.src)
+			continue
+		}
+		var path, value string
+		if n.Property != nil {
+			path = n.Property.Name
+		}
+		if n.Value != nil {
+			value = n.Value.Raw
+		}
+		if path != tt.path || value != tt.value {
+			t.Errorf("NodeAt(%q) = %q %q, want %q %q", tt.src, path, value, tt.path, tt.value)
+		}
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	src, offset := cursor("PORT: 4222\nport: $P|ORT\nhost: $HOST\n")
+	doc, err := ParseConf("test.conf", src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ResolveVariables(doc, &ResolveOptions{Env: map[string]string{"HOST": "localhost"}})
+
+	pos, ok := Definition(doc, offset)
+	if !ok || pos.Line != 1 || pos.Column != 1 {
+		t.Errorf("Definition() = %s, %v, want test.conf:1:1", pos, ok)
+	}
+
+	// Environment variables have no definition.
+	if pos, ok := Definition(doc, strings.Index(string(src), "$HOST")+1); ok {
+		t.Errorf("Definition() of an environment variable = %s", pos)
+	}
+}
@@ -0,0 +1,326 @@
+package config
+
+import (
+	"bytes"
+	"sort"
+	"strings"
+)
+
+// maxInlineArray is the maximum line length of an array of scalars to be
+// formatted on a single line.
+const maxInlineArray = 80
+
+// FormatConf formats a parsed conf document in a canonical style. Entries
+// use `:` separators, except for maps which are written as `key {`, nested
+// blocks are indented, and strings are only left unquoted if they would be
+// parsed back as the same string, e.g. `3secret` is quoted so it is not
+// mistaken for a number. Comments and blank lines between entries are
+// preserved. If the config is not nil, the keys of objects are reordered to
+// match the order of the properties in the schema.
+func FormatConf(doc *Document, cfg *Config) []byte {
+	p := confPrinter{
+		reorder: cfg != nil,
+	}
+
+	var opts []*TypeOption
+	if cfg != nil {
+		opts = []*TypeOption{{
+			Type:     "object",
+			Sections: cfg.Sections,
+		}}
+	}
+
+	p.printEntries(doc.Root, opts)
+	p.printComments(doc.Root.TrailingComments, len(doc.Root.Entries) > 0)
+
+	return p.b.Bytes()
+}
+
+// Format parses and formats the source of a conf file.
+func Format(filename string, src []byte, cfg *Config) ([]byte, error) {
+	doc, err := ParseConf(filename, src)
+	if err != nil {
+		return nil, err
+	}
+	return FormatConf(doc, cfg), nil
+}
+
+// NormalizeKeys rewrites the keys of the document that are aliases to the
+// canonical property name, e.g. `leaf` to `leafnodes` and `monitor_port` to
+// `http_port`, and returns the number of rewritten keys. A key is left as
+// is if its property is also set by another key in the same block, which
+// ValidateDocument reports as a conflict, or if it defines a referenced
+// variable. Keys differing from the name only by case are not aliases and
+// the entries of included files are not rewritten.
+func NormalizeKeys(cfg *Config, doc *Document) int {
+	vars := make(map[string]bool)
+	collectVariables(doc.Root, vars)
+	return normalizeMap(doc.Root, rootOptions(cfg), vars)
+}
+
+func normalizeMap(m *Value, opts []*TypeOption, vars map[string]bool) int {
+	n := 0
+	idx := objectIndex(derefOptions(opts))
+
+	count := make(map[*Property]int)
+	for _, e := range mapEntries(m) {
+		if p := idx[strings.ToLower(e.Key)]; p != nil && !e.Include {
+			count[p]++
+		}
+	}
+
+	for _, e := range m.Entries {
+		if e.Include {
+			continue
+		}
+		p := idx[strings.ToLower(e.Key)]
+		if p != nil && count[p] == 1 && !vars[e.Key] && !strings.EqualFold(e.Key, p.Name) {
+			e.Key = p.Name
+			e.KeyQuote = 0
+			n++
+		}
+		n += normalizeValue(e.Value, entryOptions(opts, e.Key), vars)
+	}
+	return n
+}
+
+func normalizeValue(v *Value, opts []*TypeOption, vars map[string]bool) int {
+	switch v.Kind {
+	case MapValue:
+		return normalizeMap(v, opts, vars)
+	case ArrayValue:
+		n := 0
+		inner := unwrapOptions(derefOptions(opts), ArrayValue)
+		for _, x := range v.Elems {
+			n += normalizeValue(x, inner, vars)
+		}
+		return n
+	}
+	return 0
+}
+
+type confPrinter struct {
+	b       bytes.Buffer
+	indent  int
+	reorder bool
+}
+
+func (p *confPrinter) writeIndent() {
+	p.b.WriteString(strings.Repeat(" ", p.indent*defaulTabSize))
+}
+
+// printComments prints comments on their own lines. If blanks is true,
+// empty lines preceding the comments are preserved.
+func (p *confPrinter) printComments(comments []*Comment, blanks bool) {
+	for i, c := range comments {
+		if c.BlankBefore && (blanks || i > 0) {
+			p.b.WriteByte('\n')
+		}
+		p.writeIndent()
+		p.b.WriteString(c.Text)
+		p.b.WriteByte('\n')
+	}
+}
+
+// printLineComment prints the comment following a value, if any, and
+// terminates the line.
+func (p *confPrinter) printLineComment(c *Comment) {
+	if c != nil {
+		p.b.WriteByte(' ')
+		p.b.WriteString(c.Text)
+	}
+	p.b.WriteByte('\n')
+}
+
+// printEntries prints the entries of a map, one per line. The options of
+// the map are used to look up the properties of the entries.
+func (p *confPrinter) printEntries(m *Value, opts []*TypeOption) {
+	header, entries, props, inner := p.orderEntries(m.Entries, opts)
+
+	if len(header) > 0 {
+		p.printComments(header, false)
+		p.b.WriteByte('\n')
+	}
+
+	for i, e := range entries {
+		if e.BlankBefore && i > 0 {
+			p.b.WriteByte('\n')
+		}
+		p.printComments(e.Comments, i > 0)
+		if n := len(e.Comments); n > 0 && e.Comments[n-1].BlankAfter {
+			p.b.WriteByte('\n')
+		}
+
+		p.writeIndent()
+
+		if e.Include {
+			p.b.WriteString("include ")
+			if e.Value.Quote != 0 {
+				p.b.WriteString(quoteConf(e.Value.Str))
+			} else {
+				p.b.WriteString(e.Value.Raw)
+			}
+			p.printLineComment(e.LineComment)
+			continue
+		}
+
+		p.b.WriteString(confKey(e.Key))
+		if e.Value.Kind == MapValue {
+			p.b.WriteByte(' ')
+		} else {
+			p.b.WriteString(": ")
+		}
+
+		vopts := inner
+		if prop := props[strings.ToLower(e.Key)]; prop != nil {
+			vopts = prop.Types
+		}
+		p.printValue(e.Value, vopts)
+		p.printLineComment(e.LineComment)
+	}
+}
+
+// orderEntries returns the entries of a map, reordered to match the
+// schema if enabled, along with the index of the properties of an object
+// or the options of the values of a map of values. Entries that are not
+// properties, such as variable definitions, are kept before the properties
+// and include directives are kept in place. When reordering, the header
+// comments of the map are returned separately.
+func (p *confPrinter) orderEntries(entries []*Entry, opts []*TypeOption) (header []*Comment, ordered []*Entry, props map[string]*Property, inner []*TypeOption) {
+	opts = derefOptions(opts)
+	if inner := unwrapOptions(opts, MapValue); len(inner) > 0 {
+		return nil, entries, nil, inner
+	}
+
+	var sections []*Section
+	for _, o := range opts {
+		if o.Type == "object" && !isContainer(o) {
+			sections = o.Sections
+			break
+		}
+	}
+	if len(sections) == 0 {
+		return nil, entries, nil, nil
+	}
+
+	props = propertyIndex(sections)
+	if !p.reorder {
+		return nil, entries, props, nil
+	}
+
+	rank := make(map[*Property]int)
+	for _, s := range sections {
+		for _, x := range s.Properties {
+			rank[x] = len(rank) + 1
+		}
+	}
+
+	ordered = make([]*Entry, len(entries))
+	copy(ordered, entries)
+
+	// Comments separated from the first entry by an empty line, such as a
+	// file header, stay in place.
+	if len(ordered) > 0 {
+		first := *ordered[0]
+		if n := len(first.Comments); n > 0 && first.Comments[n-1].BlankAfter {
+			header = first.Comments
+			first.Comments = nil
+			first.BlankBefore = false
+			ordered[0] = &first
+		}
+	}
+
+	// Entries are only reordered between include directives since the
+	// included entries may be overridden.
+	start := 0
+	for i := 0; i <= len(ordered); i++ {
+		if i < len(ordered) && !ordered[i].Include {
+			continue
+		}
+		run := ordered[start:i]
+		sort.SliceStable(run, func(a, b int) bool {
+			return rank[props[strings.ToLower(run[a].Key)]] < rank[props[strings.ToLower(run[b].Key)]]
+		})
+		start = i + 1
+	}
+
+	return header, ordered, props, nil
+}
+
+// printValue prints a value following a key or within an array.
+func (p *confPrinter) printValue(v *Value, opts []*TypeOption) {
+	switch v.Kind {
+	case MapValue:
+		if len(v.Entries) == 0 && len(v.TrailingComments) == 0 {
+			p.b.WriteString("{}")
+			return
+		}
+		p.b.WriteString("{\n")
+		p.indent++
+		p.printEntries(v, opts)
+		p.printComments(v.TrailingComments, len(v.Entries) > 0)
+		p.indent--
+		p.writeIndent()
+		p.b.WriteByte('}')
+
+	case ArrayValue:
+		inner := unwrapOptions(derefOptions(opts), ArrayValue)
+		if s, ok := inlineArray(v); ok && p.indent*defaulTabSize+len(s) <= maxInlineArray {
+			p.b.WriteString(s)
+			return
+		}
+		p.b.WriteString("[\n")
+		p.indent++
+		for i, x := range v.Elems {
+			p.printComments(x.Comments, i > 0)
+			p.writeIndent()
+			p.printValue(x, inner)
+			p.printLineComment(x.LineComment)
+		}
+		p.printComments(v.TrailingComments, len(v.Elems) > 0)
+		p.indent--
+		p.writeIndent()
+		p.b.WriteByte(']')
+
+	default:
+		p.b.WriteString(formatScalar(v))
+	}
+}
+
+// inlineArray returns the array formatted on a single line if it only
+// contains scalars and has no comments.
+func inlineArray(v *Value) (string, bool) {
+	if len(v.TrailingComments) > 0 {
+		return "", false
+	}
+	elems := make([]string, len(v.Elems))
+	for i, x := range v.Elems {
+		if x.Kind == MapValue || x.Kind == ArrayValue || x.Quote == '(' {
+			return "", false
+		}
+		if len(x.Comments) > 0 || x.LineComment != nil {
+			return "", false
+		}
+		elems[i] = formatScalar(x)
+	}
+	return "[" + strings.Join(elems, ", ") + "]", true
+}
+
+// formatScalar formats a scalar value. Quoted strings remain quoted while
+// unquoted strings are quoted if required. Numbers and booleans are kept
+// as written, e.g. `1MB` or `on`.
+func formatScalar(v *Value) string {
+	switch v.Kind {
+	case StringValue:
+		switch v.Quote {
+		case '(':
+			return v.Raw
+		case 0:
+			return confString(v.Str)
+		}
+		return quoteConf(v.Str)
+	case VariableValue:
+		return "$" + v.Str
+	}
+	return v.Raw
+}
@@ -0,0 +1,162 @@
+package config
+
+import (
+	"bytes"
+	"testing"
+)
+
+const formatSrc = `# header
+
+jetstream = {max_mem=1G, store_dir: '/data'}
+port 4222 // client port
+server_name=n1
+authorization {
+  users = [
+    {user: a, password: "3secret"}
+    # bob
+    {user: b, password: b}
+  ]
+}
+tags: [a, b, "c d"]
+text: (
+  hello
+)
+`
+
+func TestFormat(t *testing.T) {
+	const want = `# header
+
+jetstream {
+    max_mem: 1G
+    store_dir: "/data"
+}
+port: 4222 // client port
+server_name: n1
+authorization {
+    users: [
+        {
+            user: a
+            password: "3secret"
+        }
+        # bob
+        {
+            user: b
+            password: b
+        }
+    ]
+}
+tags: [a, b, "c d"]
+text: (
+  hello
+)
+`
+
+	got, err := Format("test.conf", []byte(formatSrc), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("Format() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFormatReorder(t *testing.T) {
+	cfg, err := DefaultConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const src = "server_name: n1\nport: 4222\nhost: 0.0.0.0\n"
+	const want = "host: \"0.0.0.0\"\nport: 4222\nserver_name: n1\n"
+
+	got, err := Format("test.conf", []byte(src), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("Format() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestFormatIdempotent(t *testing.T) {
+	cfg, err := DefaultConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ref bytes.Buffer
+	if err := GenerateConfig(&ref, cfg.Sections); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{"sample", formatSrc},
+		{"reference", ref.String()},
+		{"comments", "a: 1 # one\n\n# two\n\nb [\n  1 // first\n  2\n  # end\n]\n# trailing\n"},
+		{"quoting", "a: \"3secret\"\nb: \"true\"\nc: 'single'\nd: \"tab\\there\"\ne: \"$x\"\n"},
+	}
+
+	for _, tt := range tests {
+		for _, c := range []*Config{nil, cfg} {
+			once, err := Format(tt.name, []byte(tt.src), c)
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+				continue
+			}
+			twice, err := Format(tt.name, once, c)
+			if err != nil {
+				t.Errorf("%s: formatted output does not parse: %v", tt.name, err)
+				continue
+			}
+			if !bytes.Equal(once, twice) {
+				t.Errorf("%s (reorder %v): formatting is not idempotent\nonce:\n%s\ntwice:\n%s", tt.name, c != nil, once, twice)
+			}
+		}
+	}
+}
+
+func TestNormalizeKeys(t *testing.T) {
+	cfg, err := DefaultConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const src = `max_mem: 1G
+leaf {
+  port: 7422
+}
+jetstream {
+  max_mem: $max_mem
+  max_file: 10G
+  max_file_store: 20G
+}
+monitor_port: 8222
+`
+	// Keys which may define a referenced variable, or conflict with
+	// another key setting the same property, are left as is.
+	const want = `max_mem: 1G
+leafnodes {
+    port: 7422
+}
+jetstream {
+    max_mem: $max_mem
+    max_file: 10G
+    max_file_store: 20G
+}
+http_port: 8222
+`
+
+	doc, err := ParseConf("test.conf", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := NormalizeKeys(cfg, doc); n != 2 {
+		t.Errorf("NormalizeKeys() = %d, want 2", n)
+	}
+	if got := FormatConf(doc, nil); string(got) != want {
+		t.Errorf("NormalizeKeys() =\n%s\nwant\n%s", got, want)
+	}
+}
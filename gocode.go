@@ -0,0 +1,389 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// goInitialisms are the words of property names written in upper case or
+// with a particular case in Go names, e.g. `tls` as `TLS`.
+var goInitialisms = map[string]string{
+	"acl":       "ACL",
+	"api":       "API",
+	"cpu":       "CPU",
+	"dns":       "DNS",
+	"http":      "HTTP",
+	"https":     "HTTPS",
+	"id":        "ID",
+	"ip":        "IP",
+	"jetstream": "JetStream",
+	"jwt":       "JWT",
+	"mqtt":      "MQTT",
+	"nkey":      "NKey",
+	"nkeys":     "NKeys",
+	"ocsp":      "OCSP",
+	"tcp":       "TCP",
+	"tls":       "TLS",
+	"ttl":       "TTL",
+	"uri":       "URI",
+	"url":       "URL",
+	"urls":      "URLs",
+}
+
+// GoConfig configures the generated Go code.
+type GoConfig struct {
+	// Package is the name of the package. Defaults to `config`.
+	Package string
+
+	// Root is the name of the struct of the top-level config. Defaults
+	// to `Config`.
+	Root string
+}
+
+// GenerateGo generates Go types for the config. Each object type is a
+// struct with a field for each property, tagged with the property name
+// and its aliases, e.g. `conf:"max_payload"`. Durations are time.Duration
+// and storage sizes are int64. Fields other than slices and maps are
+// pointers, so an unset property is distinguished from one explicitly set
+// to the zero value, e.g. `logtime: false`. Properties with multiple
+// types, such as `jetstream`, are a union struct with a pointer field for
+// each type of which only one is set. Tag options after the names
+// describe the schema type where the Go type is ambiguous, e.g.
+// `type=storage`, or the choices of a string, e.g. `choices=full|cache`.
+func GenerateGo(w io.Writer, c *Config, gc *GoConfig) error {
+	pkg, root := "config", "Config"
+	if gc != nil && gc.Package != "" {
+		pkg = gc.Package
+	}
+	if gc != nil && gc.Root != "" {
+		root = gc.Root
+	}
+
+	g := goGenerator{
+		names:  make(map[string]bool),
+		types:  make(map[string]string),
+		unions: make(map[string]string),
+	}
+
+	d := g.declare(root, "", fmt.Sprintf("%s is the top-level config.", root))
+	g.fields(d, c.Sections)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by server-config. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n\n", pkg)
+	if g.time {
+		fmt.Fprintf(&b, "import \"time\"\n\n")
+	}
+
+	for _, d := range g.decls {
+		writeGoComment(&b, d.doc)
+		fmt.Fprintf(&b, "type %s struct {\n", d.name)
+		for i, f := range d.fields {
+			if i > 0 && f.doc != "" {
+				b.WriteByte('\n')
+			}
+			writeGoComment(&b, f.doc)
+			fmt.Fprintf(&b, "%s %s `conf:%q`\n", f.name, f.typ, f.tag)
+		}
+		fmt.Fprintf(&b, "}\n\n")
+	}
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return fmt.Errorf("format go source: %w", err)
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+type goDecl struct {
+	name   string
+	doc    string
+	fields []*goField
+}
+
+type goField struct {
+	name string
+	typ  string
+	tag  string
+	doc  string
+}
+
+type goGenerator struct {
+	decls []*goDecl
+
+	// names are the Go names already declared.
+	names map[string]bool
+
+	// types maps the names of type definitions to their struct so types
+	// used by multiple properties, such as `tls`, are declared once.
+	types map[string]string
+
+	// unions maps the fields of union structs to their name.
+	unions map[string]string
+
+	// time is true if the time package is used.
+	time bool
+}
+
+// declare adds a struct with a unique name. A name already declared is
+// prefixed by the name of the enclosing struct.
+func (g *goGenerator) declare(name, parent, doc string) *goDecl {
+	n := name
+	if g.names[n] && parent != "" {
+		n = parent + name
+	}
+	for i := 2; g.names[n]; i++ {
+		n = fmt.Sprintf("%s%d", name, i)
+	}
+	g.names[n] = true
+
+	d := &goDecl{
+		name: n,
+		doc:  doc,
+	}
+	g.decls = append(g.decls, d)
+	return d
+}
+
+// fields adds a field to the struct for each property of the sections.
+func (g *goGenerator) fields(d *goDecl, sections []*Section) {
+	used := make(map[string]bool)
+	for _, s := range sections {
+		for _, p := range s.Properties {
+			name := goName(p.Name)
+			fname := name
+			for i := 2; used[fname]; i++ {
+				fname = fmt.Sprintf("%s%d", name, i)
+			}
+			used[fname] = true
+
+			typ := goNilable(g.optionsType(name, d.name, p.Types))
+
+			tag := append([]string{p.Name}, p.Aliases...)
+			tag = append(tag, goTagOptions(p.Types)...)
+
+			doc := p.Description
+			if p.Deprecation != "" {
+				doc = strings.TrimSpace(fmt.Sprintf("%s\n\nDeprecated: %s", doc, p.Deprecation))
+			}
+
+			d.fields = append(d.fields, &goField{
+				name: fname,
+				typ:  typ,
+				tag:  strings.Join(tag, ","),
+				doc:  doc,
+			})
+		}
+	}
+}
+
+// optionsType returns the Go type for a set of type options, declaring
+// any structs it requires. The hint is the name for a struct declared for
+// the options. Options sharing a container are combined, as with the JSON
+// Schema, and multiple alternatives are a union struct.
+func (g *goGenerator) optionsType(hint, parent string, opts []*TypeOption) string {
+	type alt struct {
+		label string
+		typ   string
+		tag   []string
+	}
+
+	var alts []alt
+
+	if arrays := unwrapOptions(opts, ArrayValue); len(arrays) > 0 {
+		typ := g.optionsType(hint, parent, arrays)
+		alts = append(alts, alt{label: "Array", typ: "[]" + typ, tag: []string{"type=array"}})
+	}
+
+	if maps := unwrapOptions(opts, MapValue); len(maps) > 0 {
+		typ := g.optionsType(hint, parent, maps)
+		alts = append(alts, alt{label: "Map", typ: "map[string]" + typ, tag: []string{"type=map"}})
+	}
+
+	for _, o := range opts {
+		if isContainer(o) {
+			continue
+		}
+		typ := g.baseType(hint, parent, o)
+		label := o.Name
+		if label == "" {
+			label = o.Type
+		}
+		tag := []string{"type=" + label}
+		if o.Type == "string" && len(o.Choices) > 0 {
+			tag = append(tag, "choices="+strings.Join(o.Choices, "|"))
+		}
+		alts = append(alts, alt{
+			label: goName(label),
+			typ:   typ,
+			tag:   tag,
+		})
+	}
+
+	switch len(alts) {
+	case 0:
+		return "any"
+	case 1:
+		return alts[0].typ
+	}
+
+	var fields []*goField
+	used := make(map[string]bool)
+	for _, a := range alts {
+		name := a.label
+		for i := 2; used[name]; i++ {
+			name = fmt.Sprintf("%s%d", a.label, i)
+		}
+		used[name] = true
+
+		fields = append(fields, &goField{
+			name: name,
+			typ:  goNilable(a.typ),
+			tag:  "," + strings.Join(a.tag, ","),
+		})
+	}
+
+	// Properties of the same name and types share a union.
+	var key strings.Builder
+	key.WriteString(hint + ":")
+	for _, f := range fields {
+		fmt.Fprintf(&key, "%s %s %s;", f.name, f.typ, f.tag)
+	}
+	if name, ok := g.unions[key.String()]; ok {
+		return name
+	}
+
+	d := g.declare(hint+"Value", parent, "")
+	d.doc = fmt.Sprintf("%s is one of multiple types, of which only one field is set.", d.name)
+	d.fields = fields
+	g.unions[key.String()] = d.name
+	return d.name
+}
+
+// baseType returns the Go type for the type of the option, ignoring any
+// containers.
+func (g *goGenerator) baseType(hint, parent string, o *TypeOption) string {
+	// Recursive types refer to the struct of their definition.
+	if o.Ref != "" {
+		if name, ok := g.types[o.Ref]; ok {
+			return name
+		}
+		return g.optionsType(goName(o.Ref), parent, o.Target)
+	}
+
+	switch o.Type {
+	case "string", "subject", "queue-subject", "transform":
+		return "string"
+	case "integer", "storage":
+		return "int64"
+	case "float":
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "duration":
+		g.time = true
+		return "time.Duration"
+	case "object":
+		// Objects without properties are free-form.
+		if len(o.Sections) == 0 {
+			return "map[string]any"
+		}
+		if name, ok := g.types[o.Name]; ok && o.Name != "" {
+			return name
+		}
+		name := hint
+		if o.Name != "" {
+			name = goName(o.Name)
+		}
+		d := g.declare(name, parent, "")
+		if o.Name != "" {
+			g.types[o.Name] = d.name
+			d.doc = fmt.Sprintf("%s is the `%s` type.", d.name, o.Name)
+		} else {
+			d.doc = fmt.Sprintf("%s is an object of properties.", d.name)
+		}
+		if o.Description != "" && o.Description != primitiveTypes["object"] {
+			d.doc += "\n\n" + o.Description
+		}
+		g.fields(d, o.Sections)
+		return d.name
+	}
+	return "any"
+}
+
+// goNilable returns the type of a field that is nil if not set. Slices,
+// maps, and any are nil already while other types are pointers.
+func goNilable(typ string) string {
+	if strings.HasPrefix(typ, "[]") || strings.HasPrefix(typ, "map[") || typ == "any" {
+		return typ
+	}
+	return "*" + typ
+}
+
+// goTagOptions returns the tag options describing the schema type of a
+// property where the Go type is ambiguous. Only options of a single type,
+// possibly within containers, are described.
+func goTagOptions(opts []*TypeOption) []string {
+	for len(opts) == 1 && isContainer(opts[0]) {
+		kind := MapValue
+		if opts[0].Array || opts[0].ArrayOfMap || opts[0].ArrayOfArray {
+			kind = ArrayValue
+		}
+		opts = unwrapOptions(opts, kind)
+	}
+	if len(opts) != 1 {
+		return nil
+	}
+
+	o := opts[0]
+	switch {
+	case o.Type == "storage":
+		return []string{"type=storage"}
+	case o.Type == "string" && len(o.Choices) > 0:
+		return []string{"choices=" + strings.Join(o.Choices, "|")}
+	}
+	return nil
+}
+
+// goName converts a property or type name to an exported Go name, e.g.
+// `max_payload` to `MaxPayload` and `tls_timeout` to `TLSTimeout`.
+func goName(s string) string {
+	var b strings.Builder
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range words {
+		if x, ok := goInitialisms[strings.ToLower(w)]; ok {
+			b.WriteString(x)
+			continue
+		}
+		b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+	}
+
+	name := b.String()
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "X" + name
+	}
+	return name
+}
+
+// writeGoComment writes the text as a Go comment, one line per line.
+func writeGoComment(b *bytes.Buffer, text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	for _, l := range strings.Split(text, "\n") {
+		l = strings.TrimRight(l, " \t")
+		if l == "" {
+			b.WriteString("//\n")
+			continue
+		}
+		fmt.Fprintf(b, "// %s\n", l)
+	}
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"bytes"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+)
+
+func TestGenerateGo(t *testing.T) {
+	const configYAML = `
+sections:
+  - name: Server
+    properties:
+      port:
+        type: integer
+        description: Port for client connections.
+      write_deadline:
+        type: duration
+      max_payload:
+        type: storage
+      tls:
+        type: tls
+      jetstream:
+        types: [boolean, jetstream]
+      tags:
+        type: array(string)
+`
+	const typesYAML = `
+types:
+  tls:
+    type: object
+    properties:
+      cert_file:
+        type: string
+  jetstream:
+    type: object
+    properties:
+      max_mem:
+        type: storage
+        aliases: [max_memory]
+      cipher:
+        type: string
+        choices: [aes, chacha]
+`
+
+	c, err := parseTestConfig(configYAML, typesYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := GenerateGo(&b, c, &GoConfig{Package: "nats"}); err != nil {
+		t.Fatal(err)
+	}
+
+	const want = "// Code generated by server-config. DO NOT EDIT.\n\n" +
+		"package nats\n\n" +
+		"import \"time\"\n\n" +
+		"// Config is the top-level config.\n" +
+		"type Config struct {\n" +
+		"\t// Port for client connections.\n" +
+		"\tPort          *int64          `conf:\"port\"`\n" +
+		"\tWriteDeadline *time.Duration  `conf:\"write_deadline\"`\n" +
+		"\tMaxPayload    *int64          `conf:\"max_payload,type=storage\"`\n" +
+		"\tTLS           *TLS            `conf:\"tls\"`\n" +
+		"\tJetStream     *JetStreamValue `conf:\"jetstream\"`\n" +
+		"\tTags          []string        `conf:\"tags\"`\n" +
+		"}\n\n" +
+		"// TLS is the `tls` type.\n" +
+		"type TLS struct {\n" +
+		"\tCertFile *string `conf:\"cert_file\"`\n" +
+		"}\n\n" +
+		"// JetStream is the `jetstream` type.\n" +
+		"type JetStream struct {\n" +
+		"\tMaxMem *int64  `conf:\"max_mem,max_memory,type=storage\"`\n" +
+		"\tCipher *string `conf:\"cipher,choices=aes|chacha\"`\n" +
+		"}\n\n" +
+		"// JetStreamValue is one of multiple types, of which only one field is set.\n" +
+		"type JetStreamValue struct {\n" +
+		"\tBoolean   *bool      `conf:\",type=boolean\"`\n" +
+		"\tJetStream *JetStream `conf:\",type=jetstream\"`\n" +
+		"}\n"
+	if b.String() != want {
+		t.Errorf("GenerateGo() =\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestGenerateGoTypeChecks(t *testing.T) {
+	cfg, err := DefaultConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := GenerateGo(&b, cfg, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "config.go", b.Bytes(), parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("config", fset, []*ast.File{f}, nil); err != nil {
+		t.Fatal(err)
+	}
+}
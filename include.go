@@ -0,0 +1,149 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// Include is a conf file included by another conf file.
+type Include struct {
+	// Pos is the position of the include directive.
+	Pos Pos
+
+	// Doc is the included document.
+	Doc *Document
+
+	// Includes are the files included by this file.
+	Includes []*Include
+}
+
+// ResolveIncludes parses the files included by the document, recursively,
+// and attaches them to the include directives. Paths are relative to the
+// directory of the root document. A diagnostic is returned for each
+// include that cannot be read or parsed, or that includes itself, while
+// the other includes are still resolved.
+func ResolveIncludes(doc *Document) []Diagnostic {
+	r := includeResolver{
+		dir:   filepath.Dir(doc.Filename),
+		stack: []string{filepath.Clean(doc.Filename)},
+	}
+	r.resolve(doc.Root)
+	return r.diags
+}
+
+type includeResolver struct {
+	// dir is the directory of the root document.
+	dir string
+
+	// stack is the chain of files currently being resolved.
+	stack []string
+
+	diags []Diagnostic
+}
+
+func (r *includeResolver) add(pos Pos, msg string) {
+	r.diags = append(r.diags, Diagnostic{
+		Pos:      pos,
+		Severity: SeverityError,
+		Message:  msg,
+	})
+}
+
+func (r *includeResolver) resolve(v *Value) {
+	switch v.Kind {
+	case ArrayValue:
+		for _, x := range v.Elems {
+			r.resolve(x)
+		}
+
+	case MapValue:
+		for _, e := range v.Entries {
+			if !e.Include {
+				r.resolve(e.Value)
+				continue
+			}
+			r.resolveInclude(e)
+		}
+	}
+}
+
+func (r *includeResolver) resolveInclude(e *Entry) {
+	path := e.Value.Str
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(r.dir, path)
+	}
+	path = filepath.Clean(path)
+
+	for i, f := range r.stack {
+		if f == path {
+			chain := append(append([]string{}, r.stack[i:]...), path)
+			r.add(e.Value.Pos, "include cycle: "+strings.Join(chain, " -> "))
+			return
+		}
+	}
+
+	doc, err := ParseConfFile(path)
+	if err != nil {
+		if pe, ok := err.(*ParseError); ok {
+			r.add(pe.Pos, pe.Msg)
+		} else {
+			r.add(e.Value.Pos, err.Error())
+		}
+		return
+	}
+
+	// The file is attached even if its own includes fail to resolve so
+	// the entries that were resolved can be used.
+	e.Included = doc
+
+	r.stack = append(r.stack, path)
+	r.resolve(doc.Root)
+	r.stack = r.stack[:len(r.stack)-1]
+}
+
+// Includes returns the graph of files included by the document once the
+// includes have been resolved.
+func Includes(doc *Document) []*Include {
+	return collectIncludes(doc.Root, nil)
+}
+
+func collectIncludes(v *Value, incs []*Include) []*Include {
+	switch v.Kind {
+	case ArrayValue:
+		for _, x := range v.Elems {
+			incs = collectIncludes(x, incs)
+		}
+
+	case MapValue:
+		for _, e := range v.Entries {
+			if !e.Include {
+				incs = collectIncludes(e.Value, incs)
+				continue
+			}
+			if e.Included == nil {
+				continue
+			}
+			incs = append(incs, &Include{
+				Pos:      e.KeyPos,
+				Doc:      e.Included,
+				Includes: Includes(e.Included),
+			})
+		}
+	}
+	return incs
+}
+
+// mapEntries returns the entries of a map where the include directives
+// which have been resolved are replaced by the entries of the included
+// file.
+func mapEntries(m *Value) []*Entry {
+	var entries []*Entry
+	for _, e := range m.Entries {
+		if e.Include && e.Included != nil {
+			entries = append(entries, mapEntries(e.Included.Root)...)
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestResolveIncludes(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.conf":   "include missing.conf\ninclude broken.conf\ninclude ports.conf\ninclude cycle.conf\n",
+		"broken.conf": "port: {\n",
+		"ports.conf":  "port: 4222\n",
+		"cycle.conf":  "include main.conf\n",
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	doc, err := ParseConfFile(filepath.Join(dir, "main.conf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diags := ResolveIncludes(doc)
+
+	// Each failed include is reported and the others are still resolved.
+	want := []string{
+		"main.conf:1:9: error: open ",
+		"broken.conf:1:7: error: unterminated map",
+		"cycle.conf:1:9: error: include cycle: ",
+	}
+	if len(diags) != len(want) {
+		t.Fatalf("got %d diagnostics, want %d: %v", len(diags), len(want), diags)
+	}
+	for i, d := range diags {
+		s := strings.TrimPrefix(d.String(), dir+string(filepath.Separator))
+		if !strings.HasPrefix(s, want[i]) {
+			t.Errorf("diagnostic %d = %q, want prefix %q", i, s, want[i])
+		}
+	}
+
+	var ports *Entry
+	for _, e := range mapEntries(doc.Root) {
+		if e.Key == "port" {
+			ports = e
+		}
+	}
+	if ports == nil || ports.Value.Int != 4222 {
+		t.Errorf("entries of ports.conf were not resolved after the failed includes")
+	}
+}
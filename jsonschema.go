@@ -0,0 +1,272 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+var (
+	// jsonSchemaDurationPattern matches Go durations, e.g. `1m30s`.
+	jsonSchemaDurationPattern = `^([0-9]+(\.[0-9]*)?(ns|us|µs|ms|s|m|h))+$|^0$`
+
+	// jsonSchemaStoragePattern matches a size with an optional unit,
+	// e.g. `512MB`.
+	jsonSchemaStoragePattern = `^[0-9]+\s*([kKmMgGtTpPeE]([iI]?[bB]?))?$`
+
+	// jsonSchemaSubjectPattern matches a subject of non-empty tokens
+	// without whitespace where a `>` token may only be the last, e.g.
+	// `orders.*.>`. Subject transform functions may contain whitespace
+	// while a `{` not starting one is literal, as with checkSubject.
+	jsonSchemaSubjectPattern = `^(((\{\{([^}]|\}[^}])*\}\}|\{[^.\s{]|[^.\s{>])(\{\{([^}]|\}[^}])*\}\}|\{[^.\s{]|[^.\s{])*\{?|\{|>((\{\{([^}]|\}[^}])*\}\}|\{[^.\s{]|[^.\s{])+\{?|\{))\.)*(((\{\{([^}]|\}[^}])*\}\}|\{[^.\s{]|[^.\s{>])(\{\{([^}]|\}[^}])*\}\}|\{[^.\s{]|[^.\s{])*\{?|\{|>((\{\{([^}]|\}[^}])*\}\}|\{[^.\s{]|[^.\s{])+\{?|\{))|>)$`
+
+	// jsonSchemaQueueSubjectPattern matches a subject optionally followed
+	// by a queue group, e.g. `orders.* workers`.
+	jsonSchemaQueueSubjectPattern = `^\s*` + strings.TrimSuffix(strings.TrimPrefix(jsonSchemaSubjectPattern, "^"), "$") + `(\s+\S+)?\s*$`
+)
+
+// GenerateJSONSchema generates a JSON Schema (draft 2020-12) for the config
+// which can be used to author and validate the config as JSON. Aliases are
+// included as references to the canonical property and recursive types are
+// defined in `$defs`.
+func GenerateJSONSchema(c *Config) ([]byte, error) {
+	root := jsonSchemaObject("#", c.Sections)
+	root["$schema"] = jsonSchemaDraft
+
+	refs := make(map[string][]*TypeOption)
+	collectRefs(c.Sections, refs)
+	if len(refs) > 0 {
+		defs := make(map[string]any)
+		for name, opts := range refs {
+			defs[name] = jsonSchemaOptions(fmt.Sprintf("#/$defs/%s", jsonPointerEscape(name)), opts)
+		}
+		root["$defs"] = defs
+	}
+
+	if c.Name != "" {
+		root["title"] = c.Name
+	}
+	if c.Description != "" {
+		root["description"] = c.Description
+	}
+
+	return json.MarshalIndent(root, "", "  ")
+}
+
+// collectRefs collects the options of the recursive types referenced
+// within the sections.
+func collectRefs(sections []*Section, refs map[string][]*TypeOption) {
+	for _, s := range sections {
+		for _, p := range s.Properties {
+			for _, o := range p.Types {
+				if o.Ref != "" {
+					refs[o.Ref] = o.Target
+					continue
+				}
+				collectRefs(o.Sections, refs)
+			}
+		}
+	}
+}
+
+// jsonPointerEscape escapes a JSON pointer reference token.
+func jsonPointerEscape(s string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(s)
+}
+
+// jsonSchemaObject returns the schema for an object with the properties
+// of the sections. The ptr is the JSON pointer to this schema which is used
+// for alias references.
+func jsonSchemaObject(ptr string, sections []*Section) map[string]any {
+	s := map[string]any{
+		"type": "object",
+	}
+
+	// Objects without properties are free-form.
+	if len(sections) == 0 {
+		return s
+	}
+
+	props := make(map[string]any)
+	for _, sec := range sections {
+		for _, p := range sec.Properties {
+			pptr := fmt.Sprintf("%s/properties/%s", ptr, jsonPointerEscape(p.Name))
+			props[p.Name] = jsonSchemaProperty(pptr, p)
+		}
+	}
+
+	for _, sec := range sections {
+		for _, p := range sec.Properties {
+			for _, a := range p.Aliases {
+				if _, ok := props[a]; ok {
+					continue
+				}
+				props[a] = map[string]any{
+					"$ref":        fmt.Sprintf("%s/properties/%s", ptr, jsonPointerEscape(p.Name)),
+					"description": fmt.Sprintf("Alias of `%s`.", p.Name),
+				}
+			}
+		}
+	}
+
+	s["properties"] = props
+	s["additionalProperties"] = false
+
+	return s
+}
+
+// jsonSchemaProperty returns the schema for a property.
+func jsonSchemaProperty(ptr string, p *Property) map[string]any {
+	s := jsonSchemaOptions(ptr, p.Types)
+
+	desc := p.Description
+	if p.Deprecation != "" {
+		s["deprecated"] = true
+		desc = strings.TrimSpace(fmt.Sprintf("%s\n\nDeprecated: %s", desc, p.Deprecation))
+	}
+	if desc != "" {
+		s["description"] = desc
+	}
+	if p.Default != nil {
+		s["default"] = p.Default
+	}
+
+	return s
+}
+
+// jsonSchemaOptions returns the schema for a set of type options. Options
+// sharing a container are combined, e.g. `map(string)` and
+// `map(array(T))` are a map whose values are either a string or an array,
+// and the alternatives are modeled with `oneOf`.
+func jsonSchemaOptions(ptr string, opts []*TypeOption) map[string]any {
+	var alts []map[string]any
+
+	arrays := unwrapOptions(opts, ArrayValue)
+	maps := unwrapOptions(opts, MapValue)
+
+	n := 0
+	if len(arrays) > 0 {
+		n++
+	}
+	if len(maps) > 0 {
+		n++
+	}
+	for _, o := range opts {
+		if !isContainer(o) {
+			n++
+		}
+	}
+
+	altPtr := func() string {
+		return ptr
+	}
+	if n > 1 {
+		altPtr = func() string {
+			return fmt.Sprintf("%s/oneOf/%d", ptr, len(alts))
+		}
+	}
+
+	if len(arrays) > 0 {
+		ptr := altPtr()
+		alts = append(alts, map[string]any{
+			"type":  "array",
+			"items": jsonSchemaOptions(ptr+"/items", arrays),
+		})
+	}
+
+	if len(maps) > 0 {
+		ptr := altPtr()
+		m := map[string]any{
+			"type":                 "object",
+			"additionalProperties": jsonSchemaOptions(ptr+"/additionalProperties", maps),
+		}
+		if k := mapKeyType(opts); k != "" {
+			m["propertyNames"] = jsonSchemaBase(ptr+"/propertyNames", &TypeOption{Type: k})
+		}
+		alts = append(alts, m)
+	}
+
+	for _, o := range opts {
+		if !isContainer(o) {
+			alts = append(alts, jsonSchemaBase(altPtr(), o))
+		}
+	}
+
+	switch len(alts) {
+	case 0:
+		return make(map[string]any)
+	case 1:
+		return alts[0]
+	}
+
+	oneOf := make([]any, len(alts))
+	for i, a := range alts {
+		oneOf[i] = a
+	}
+	return map[string]any{
+		"oneOf": oneOf,
+	}
+}
+
+// jsonSchemaBase returns the schema for the type of the option, ignoring
+// any containers.
+func jsonSchemaBase(ptr string, o *TypeOption) map[string]any {
+	// Recursive types refer to their definition.
+	if o.Ref != "" {
+		s := map[string]any{
+			"$ref": fmt.Sprintf("#/$defs/%s", jsonPointerEscape(o.Ref)),
+		}
+		if o.Description != "" {
+			s["description"] = o.Description
+		}
+		return s
+	}
+
+	var s map[string]any
+
+	switch o.Type {
+	case "string":
+		s = map[string]any{"type": "string"}
+		if len(o.Choices) > 0 {
+			s["enum"] = o.Choices
+		}
+	case "integer":
+		s = map[string]any{"type": "integer"}
+	case "float":
+		s = map[string]any{"type": "number"}
+	case "boolean":
+		s = map[string]any{"type": "boolean"}
+	case "duration":
+		s = map[string]any{
+			"type":    "string",
+			"pattern": jsonSchemaDurationPattern,
+		}
+	case "subject", "transform":
+		s = map[string]any{
+			"type":    "string",
+			"pattern": jsonSchemaSubjectPattern,
+		}
+	case "queue-subject":
+		s = map[string]any{
+			"type":    "string",
+			"pattern": jsonSchemaQueueSubjectPattern,
+		}
+	case "storage":
+		s = map[string]any{
+			"type":    []string{"integer", "string"},
+			"pattern": jsonSchemaStoragePattern,
+		}
+	case "object":
+		s = jsonSchemaObject(ptr, o.Sections)
+	default:
+		s = make(map[string]any)
+	}
+
+	if o.Description != "" {
+		s["description"] = o.Description
+	}
+
+	return s
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"encoding/json"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// resolvePointer resolves a JSON pointer such as `#/properties/port`
+// within the schema.
+func resolvePointer(root any, ptr string) (any, bool) {
+	v := root
+	for _, tok := range strings.Split(strings.TrimPrefix(ptr, "#"), "/")[1:] {
+		tok = strings.NewReplacer("~1", "/", "~0", "~").Replace(tok)
+		switch x := v.(type) {
+		case map[string]any:
+			var ok bool
+			if v, ok = x[tok]; !ok {
+				return nil, false
+			}
+		case []any:
+			i, err := strconv.Atoi(tok)
+			if err != nil || i < 0 || i >= len(x) {
+				return nil, false
+			}
+			v = x[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+func TestGenerateJSONSchema(t *testing.T) {
+	cfg, err := DefaultConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := GenerateJSONSchema(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var root map[string]any
+	if err := json.Unmarshal(b, &root); err != nil {
+		t.Fatal(err)
+	}
+	if root["$schema"] != jsonSchemaDraft {
+		t.Errorf("$schema = %v, want %s", root["$schema"], jsonSchemaDraft)
+	}
+
+	tests := []struct {
+		ptr  string
+		key  string
+		want any
+	}{
+		{"#/properties/port", "type", "integer"},
+		{"#/properties/leaf", "$ref", "#/properties/leafnodes"},
+		{"#/properties/store_dir", "deprecated", true},
+		{"#/properties/jetstream/oneOf/2/properties/cipher/enum/0", "", "chacha"},
+		{"#/additionalProperties", "", false},
+	}
+	for _, tt := range tests {
+		v, ok := resolvePointer(root, tt.ptr)
+		if !ok {
+			t.Errorf("%s not found", tt.ptr)
+			continue
+		}
+		if tt.key != "" {
+			v = v.(map[string]any)[tt.key]
+		}
+		if v != tt.want {
+			t.Errorf("%s %s = %v, want %v", tt.ptr, tt.key, v, tt.want)
+		}
+	}
+
+	// Every reference resolves within the schema.
+	var refs int
+	var walk func(v any)
+	walk = func(v any) {
+		switch x := v.(type) {
+		case map[string]any:
+			if ref, ok := x["$ref"].(string); ok {
+				refs++
+				if _, ok := resolvePointer(root, ref); !ok {
+					t.Errorf("unresolved $ref %s", ref)
+				}
+			}
+			for _, y := range x {
+				walk(y)
+			}
+		case []any:
+			for _, y := range x {
+				walk(y)
+			}
+		}
+	}
+	walk(root)
+	if refs == 0 {
+		t.Error("no references found")
+	}
+}
+
+func TestJSONSchemaPatterns(t *testing.T) {
+	tests := []struct {
+		pattern string
+		value   string
+		want    bool
+	}{
+		{jsonSchemaDurationPattern, "1m30s", true},
+		{jsonSchemaDurationPattern, "500ms", true},
+		{jsonSchemaDurationPattern, "1.5h", true},
+		{jsonSchemaDurationPattern, "0", true},
+		{jsonSchemaDurationPattern, "10", false},
+		{jsonSchemaDurationPattern, "1 m", false},
+		{jsonSchemaStoragePattern, "512MB", true},
+		{jsonSchemaStoragePattern, "1GiB", true},
+		{jsonSchemaStoragePattern, "64k", true},
+		{jsonSchemaStoragePattern, "1024", true},
+		{jsonSchemaStoragePattern, "1XB", false},
+		{jsonSchemaStoragePattern, "-1G", false},
+	}
+
+	for _, tt := range tests {
+		if got := regexp.MustCompile(tt.pattern).MatchString(tt.value); got != tt.want {
+			t.Errorf("%q matches %s = %v, want %v", tt.value, tt.pattern, got, tt.want)
+		}
+	}
+}
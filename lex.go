@@ -0,0 +1,344 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Pos describes a position within a conf file.
+type Pos struct {
+	// Filename is the name of the file, if any.
+	Filename string
+
+	// Offset is the byte offset, starting at 0.
+	Offset int
+
+	// Line is the line number, starting at 1.
+	Line int
+
+	// Column is the byte offset within the line, starting at 1.
+	Column int
+}
+
+// IsValid reports whether the position is valid.
+func (p Pos) IsValid() bool {
+	return p.Line > 0
+}
+
+// String returns the position formatted as `file:line:column`, omitting
+// the parts that are unknown.
+func (p Pos) String() string {
+	s := p.Filename
+	if p.IsValid() {
+		if s != "" {
+			s += ":"
+		}
+		s += fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	if s == "" {
+		s = "-"
+	}
+	return s
+}
+
+// ParseError is returned when a conf file is syntactically invalid, a
+// schema file is malformed, or a conf value cannot be unmarshaled.
+type ParseError struct {
+	Pos Pos
+	Msg string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+const eof = -1
+
+// lexer scans the raw bytes of a conf file. Unlike a traditional lexer,
+// it does not produce a stream of tokens since whether text is a key or
+// a value depends on where the parser is, e.g. a colon terminates a bare
+// key, but it is part of a bare value such as `127.0.0.1:4222`.
+type lexer struct {
+	filename string
+	src      []byte
+	off      int
+	line     int
+	col      int
+}
+
+func newLexer(filename string, src []byte) *lexer {
+	return &lexer{
+		filename: filename,
+		src:      src,
+		line:     1,
+		col:      1,
+	}
+}
+
+func (l *lexer) pos() Pos {
+	return Pos{
+		Filename: l.filename,
+		Offset:   l.off,
+		Line:     l.line,
+		Column:   l.col,
+	}
+}
+
+func (l *lexer) errorf(pos Pos, format string, args ...any) error {
+	return &ParseError{
+		Pos: pos,
+		Msg: fmt.Sprintf(format, args...),
+	}
+}
+
+// peek returns the next byte without consuming it.
+func (l *lexer) peek() int {
+	if l.off >= len(l.src) {
+		return eof
+	}
+	return int(l.src[l.off])
+}
+
+// peekAt returns the byte n positions ahead without consuming it.
+func (l *lexer) peekAt(n int) int {
+	if l.off+n >= len(l.src) {
+		return eof
+	}
+	return int(l.src[l.off+n])
+}
+
+// next consumes and returns the next byte.
+func (l *lexer) next() int {
+	if l.off >= len(l.src) {
+		return eof
+	}
+	c := l.src[l.off]
+	l.off++
+	if c == '\n' {
+		l.line++
+		l.col = 1
+	} else {
+		l.col++
+	}
+	return int(c)
+}
+
+// skipSpace skips whitespace, excluding newlines.
+func (l *lexer) skipSpace() {
+	for {
+		switch l.peek() {
+		case ' ', '\t', '\r':
+			l.next()
+		default:
+			return
+		}
+	}
+}
+
+// atComment reports whether a comment starts at the current position.
+func (l *lexer) atComment() bool {
+	c := l.peek()
+	return c == '#' || (c == '/' && l.peekAt(1) == '/')
+}
+
+// comment consumes a comment up to, but excluding, the newline.
+func (l *lexer) comment() *Comment {
+	pos := l.pos()
+	start := l.off
+	for c := l.peek(); c != '\n' && c != eof; c = l.peek() {
+		l.next()
+	}
+	return &Comment{
+		Pos:  pos,
+		Text: strings.TrimRight(string(l.src[start:l.off]), " \t\r"),
+	}
+}
+
+// blank skips whitespace, newlines, and comments, returning the comments
+// found. If seps is true, stray `,` and `;` terminators are skipped as well.
+// The returned boolean indicates whether an empty line was skipped after the
+// last comment, if any.
+func (l *lexer) blank(seps bool) ([]*Comment, bool) {
+	var (
+		comments []*Comment
+		newlines int
+	)
+	for {
+		switch c := l.peek(); {
+		case c == ' ' || c == '\t' || c == '\r':
+			l.next()
+		case c == '\n':
+			newlines++
+			l.next()
+		case seps && (c == ',' || c == ';'):
+			l.next()
+		case l.atComment():
+			cm := l.comment()
+			cm.BlankBefore = newlines > 1
+			if n := len(comments); n > 0 {
+				comments[n-1].BlankAfter = cm.BlankBefore
+			}
+			comments = append(comments, cm)
+			newlines = 0
+		default:
+			if n := len(comments); n > 0 {
+				comments[n-1].BlankAfter = newlines > 1
+			}
+			return comments, newlines > 1
+		}
+	}
+}
+
+func isSpace(c int) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// isKeyEnd reports whether the byte terminates a bare key.
+func isKeyEnd(c int) bool {
+	switch c {
+	case eof, '=', ':', '{', '}', '[', ']', ',', ';', '#', '"', '\'':
+		return true
+	}
+	return isSpace(c)
+}
+
+// isValueEnd reports whether the byte terminates a bare value. Note that
+// comment markers do not terminate a value so that URLs such as
+// `nats://localhost:4222` are kept intact.
+func isValueEnd(c int) bool {
+	switch c {
+	case eof, ',', ';', '}', ']':
+		return true
+	}
+	return isSpace(c)
+}
+
+func isVariableChar(c int) bool {
+	return c == '_' || c == '-' ||
+		(c >= 'a' && c <= 'z') ||
+		(c >= 'A' && c <= 'Z') ||
+		(c >= '0' && c <= '9')
+}
+
+// bareKey consumes an unquoted key.
+func (l *lexer) bareKey() string {
+	start := l.off
+	for !isKeyEnd(l.peek()) {
+		l.next()
+	}
+	return string(l.src[start:l.off])
+}
+
+// bareValue consumes an unquoted value.
+func (l *lexer) bareValue() string {
+	start := l.off
+	for !isValueEnd(l.peek()) {
+		l.next()
+	}
+	return string(l.src[start:l.off])
+}
+
+// quoted consumes a quoted string, returning the raw text and the
+// decoded value. Double-quoted strings support escape sequences while
+// single-quoted strings are taken literally.
+func (l *lexer) quoted() (string, string, error) {
+	pos := l.pos()
+	start := l.off
+	q := l.next()
+
+	var b strings.Builder
+	for {
+		c := l.next()
+		switch {
+		case c == eof || c == '\n':
+			return "", "", l.errorf(pos, "unterminated quoted string")
+
+		case c == q:
+			return string(l.src[start:l.off]), b.String(), nil
+
+		case c == '\\' && q == '"':
+			epos := l.pos()
+			e := l.next()
+			switch e {
+			case 't':
+				b.WriteByte('\t')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case '"':
+				b.WriteByte('"')
+			case '\\':
+				b.WriteByte('\\')
+			case 'u', 'U':
+				n := 4
+				if e == 'U' {
+					n = 8
+				}
+				if l.off+n > len(l.src) {
+					return "", "", l.errorf(epos, "invalid unicode escape")
+				}
+				hex := string(l.src[l.off : l.off+n])
+				r, err := strconv.ParseUint(hex, 16, 32)
+				if err != nil || !utf8.ValidRune(rune(r)) {
+					return "", "", l.errorf(epos, "invalid unicode escape %q", hex)
+				}
+				for i := 0; i < n; i++ {
+					l.next()
+				}
+				b.WriteRune(rune(r))
+			default:
+				return "", "", l.errorf(epos, "invalid escape sequence \\%c", e)
+			}
+
+		default:
+			b.WriteByte(byte(c))
+		}
+	}
+}
+
+// atBlockString reports whether a block string starts at the current
+// position, i.e. an opening parenthesis followed by a newline.
+func (l *lexer) atBlockString() bool {
+	if l.peek() != '(' {
+		return false
+	}
+	for i := l.off + 1; i < len(l.src); i++ {
+		switch l.src[i] {
+		case ' ', '\t', '\r':
+		case '\n':
+			return true
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// blockString consumes a block string which spans the lines between the
+// opening parenthesis and a line containing only a closing parenthesis.
+func (l *lexer) blockString() (string, string, error) {
+	pos := l.pos()
+	start := l.off
+	for l.next() != '\n' {
+	}
+
+	var lines []string
+	for {
+		if l.peek() == eof {
+			return "", "", l.errorf(pos, "unterminated block string")
+		}
+		lstart := l.off
+		for c := l.peek(); c != '\n' && c != eof; c = l.peek() {
+			l.next()
+		}
+		line := string(l.src[lstart:l.off])
+		if strings.TrimSpace(line) == ")" {
+			return string(l.src[start:l.off]), strings.Join(lines, "\n"), nil
+		}
+		lines = append(lines, line)
+		l.next()
+	}
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+)
+
+// Lint checks the config and type definition files, returning all problems
+// found rather than only the first, such as unknown fields. Problems which
+// require the types to be parsed, such as unknown types or cycles, are only
+// checked once the files are otherwise valid.
+func Lint(path string, typePaths []string) []error {
+	var errs []error
+
+	check := func(path string, v any) {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			return
+		}
+		_, ferrs := checkYAML(path, b, v)
+		errs = append(errs, ferrs...)
+	}
+
+	check(path, &yamlConfig{})
+	for _, p := range typePaths {
+		check(p, &yamlFile{})
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	if _, err := Parse(path, typePaths); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	typesPath := filepath.Join(dir, "types.yaml")
+
+	write := func(path, src string) {
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lint := func() []string {
+		var msgs []string
+		for _, err := range Lint(configPath, []string{typesPath}) {
+			msgs = append(msgs, strings.TrimPrefix(err.Error(), dir+string(filepath.Separator)))
+		}
+		return msgs
+	}
+
+	// Unknown fields are reported for all files.
+	write(configPath, "nmae: Config\nsections:\n  - name: Connectivity\n    properties:\n      port:\n        type: port\n        defualt: 4222\n")
+	write(typesPath, "types:\n  port:\n    type: integer\n    descripton: A port.\n")
+	want := []string{
+		`config.yaml:1:1: unknown field "nmae", did you mean "name"?`,
+		`config.yaml:7:9: unknown field "defualt", did you mean "default"?`,
+		`types.yaml:4:5: unknown field "descripton", did you mean "description"?`,
+	}
+	if got := lint(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Lint() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// ParseLenient ignores them.
+	if _, err := ParseLenient(configPath, []string{typesPath}); err != nil {
+		t.Errorf("ParseLenient: %v", err)
+	}
+
+	// Once the fields are valid, the types are checked.
+	write(configPath, "name: Config\nsections:\n  - name: Connectivity\n    properties:\n      port:\n        type: prot\n")
+	write(typesPath, "types:\n  port:\n    type: integer\n")
+	want = []string{`unknown type "prot"`}
+	if got := lint(); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Lint() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	write(configPath, "name: Config\nsections:\n  - name: Connectivity\n    properties:\n      port:\n        type: port\n")
+	if got := lint(); len(got) > 0 {
+		t.Errorf("Lint() = %v, want no problems", got)
+	}
+}
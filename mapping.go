@@ -0,0 +1,361 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SubjectMapping maps messages published to a source subject to one or
+// more weighted destinations, as defined by `mappings`.
+type SubjectMapping struct {
+	// Source is the source subject, which may contain wildcards.
+	Source string
+
+	// Pos is the position of the key of the mapping.
+	Pos Pos
+
+	// Destinations are the destinations in the order they are defined.
+	Destinations []*MappingDestination
+}
+
+// MappingDestination is a destination of a subject mapping.
+type MappingDestination struct {
+	// Subject is the destination subject, which may contain transform
+	// functions such as `{{wildcard(1)}}`.
+	Subject string
+
+	// Weight is the percentage of messages mapped to the destination,
+	// 100 if not set.
+	Weight int
+
+	// Cluster is the cluster the destination is scoped to, if any.
+	Cluster string
+
+	// Pos is the position of the destination subject.
+	Pos Pos
+
+	transform *transform
+}
+
+// ParseMappings parses the value of a `mappings` property, whose entries
+// map a source subject to either a destination subject or an array of
+// destinations with a weight and cluster. Variables must have been
+// resolved. The transforms of each destination are checked against the
+// source subject, e.g. `{{wildcard(2)}}` requires two wildcards.
+func ParseMappings(v *Value) ([]*SubjectMapping, error) {
+	v = resolvedValue(v)
+	if v.Kind != MapValue {
+		return nil, &ParseError{Pos: v.Pos, Msg: fmt.Sprintf("mappings must be a map, got %s", describeValue(v))}
+	}
+
+	var ms []*SubjectMapping
+	for _, e := range mapEntries(v) {
+		if e.Include {
+			continue
+		}
+		m := &SubjectMapping{
+			Source: e.Key,
+			Pos:    e.KeyPos,
+		}
+
+		val := resolvedValue(e.Value)
+		switch val.Kind {
+		case ArrayValue:
+			for _, x := range val.Elems {
+				d, err := parseMappingDestination(resolvedValue(x))
+				if err != nil {
+					return nil, err
+				}
+				m.Destinations = append(m.Destinations, d)
+			}
+		default:
+			s, ok := mappingString(val)
+			if !ok {
+				return nil, &ParseError{Pos: val.Pos, Msg: fmt.Sprintf("mapping %q: invalid destination %s", e.Key, describeValue(val))}
+			}
+			m.Destinations = append(m.Destinations, &MappingDestination{
+				Subject: s,
+				Weight:  100,
+				Pos:     val.Pos,
+			})
+		}
+
+		for _, d := range m.Destinations {
+			t, err := parseTransform(m.Source, d.Subject)
+			if err != nil {
+				return nil, &ParseError{Pos: d.Pos, Msg: fmt.Sprintf("mapping %q: %s", m.Source, err)}
+			}
+			d.transform = t
+		}
+		ms = append(ms, m)
+	}
+	return ms, nil
+}
+
+// parseMappingDestination parses a destination map, e.g. `{destination:
+// foo.v1, weight: 90%, cluster: west}`.
+func parseMappingDestination(v *Value) (*MappingDestination, error) {
+	if v.Kind != MapValue {
+		return nil, &ParseError{Pos: v.Pos, Msg: fmt.Sprintf("mapping destination must be a map, got %s", describeValue(v))}
+	}
+
+	d := &MappingDestination{
+		Weight: 100,
+		Pos:    v.Pos,
+	}
+	hasDest := false
+	for _, e := range mapEntries(v) {
+		if e.Include {
+			continue
+		}
+		x := resolvedValue(e.Value)
+		switch strings.ToLower(e.Key) {
+		case "destination", "dest":
+			s, ok := mappingString(x)
+			if !ok {
+				return nil, &ParseError{Pos: x.Pos, Msg: fmt.Sprintf("invalid destination %s", describeValue(x))}
+			}
+			d.Subject, d.Pos, hasDest = s, x.Pos, true
+		case "weight":
+			w, err := parseWeight(x)
+			if err != nil {
+				return nil, &ParseError{Pos: x.Pos, Msg: err.Error()}
+			}
+			d.Weight = w
+		case "cluster":
+			s, ok := mappingString(x)
+			if !ok {
+				return nil, &ParseError{Pos: x.Pos, Msg: fmt.Sprintf("invalid cluster %s", describeValue(x))}
+			}
+			d.Cluster = s
+		}
+	}
+	if !hasDest {
+		return nil, &ParseError{Pos: v.Pos, Msg: "mapping destination has no destination"}
+	}
+	return d, nil
+}
+
+// parseWeight parses a weight between 0 and 100, e.g. `90` or `90%`.
+func parseWeight(v *Value) (int, error) {
+	var (
+		n   int64
+		err error
+	)
+	switch v.Kind {
+	case IntegerValue:
+		n = v.Int
+	case StringValue:
+		n, err = strconv.ParseInt(strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(v.Str), "%")), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid weight %q", v.Str)
+		}
+	default:
+		return 0, fmt.Errorf("invalid weight %s", describeValue(v))
+	}
+	if n < 0 || n > 100 {
+		return 0, fmt.Errorf("weight %d is not between 0 and 100", n)
+	}
+	return int(n), nil
+}
+
+// mappingString returns the value of a scalar as a string.
+func mappingString(v *Value) (string, bool) {
+	switch v.Kind {
+	case StringValue:
+		return v.Str, true
+	case IntegerValue, FloatValue, BoolValue:
+		return v.Raw, true
+	}
+	return "", false
+}
+
+// resolvedValue returns the value a resolved variable refers to, or the
+// value itself.
+func resolvedValue(v *Value) *Value {
+	if v.Kind == VariableValue && v.Resolved != nil {
+		return v.Resolved
+	}
+	return v
+}
+
+// MatchMapping returns the first mapping whose source subject matches the
+// subject, as the server does, or nil if there is none.
+func MatchMapping(ms []*SubjectMapping, subject string) *SubjectMapping {
+	for _, m := range ms {
+		if len(m.Destinations) > 0 && m.Destinations[0].transform.match(subject) != nil {
+			return m
+		}
+	}
+	return nil
+}
+
+// ClusterDestinations returns the destinations that apply to a server in
+// the cluster: those scoped to the cluster if there are any, otherwise
+// those not scoped to a cluster.
+func (m *SubjectMapping) ClusterDestinations(cluster string) []*MappingDestination {
+	var scoped, unscoped []*MappingDestination
+	for _, d := range m.Destinations {
+		switch d.Cluster {
+		case "":
+			unscoped = append(unscoped, d)
+		case cluster:
+			scoped = append(scoped, d)
+		}
+	}
+	if cluster != "" && len(scoped) > 0 {
+		return scoped
+	}
+	return unscoped
+}
+
+// Apply returns the subject a message published to the subject is mapped
+// to. The subject must match the source subject of the mapping.
+func (d *MappingDestination) Apply(subject string) (string, error) {
+	return d.transform.apply(subject)
+}
+
+// DocumentMappings returns the mappings defined by the top-level
+// `mappings` of a document or, if the account is not empty, by the
+// `mappings` of the account within `accounts`. Includes and variables must
+// have been resolved. It returns nil if there are no mappings.
+func DocumentMappings(cfg *Config, doc *Document, account string) ([]*SubjectMapping, error) {
+	m, opts := doc.Root, rootOptions(cfg)
+	if account != "" {
+		accounts := findEntry(m, opts, "accounts")
+		if accounts == nil {
+			return nil, fmt.Errorf("accounts are not defined")
+		}
+		opts = entryOptions(opts, accounts.Key)
+
+		var acc *Entry
+		for _, e := range mapEntries(resolvedValue(accounts.Value)) {
+			if !e.Include && e.Key == account {
+				acc = e
+			}
+		}
+		if acc == nil {
+			return nil, fmt.Errorf("account %q is not defined", account)
+		}
+		m, opts = resolvedValue(acc.Value), entryOptions(opts, acc.Key)
+	}
+
+	e := findEntry(m, opts, "mappings")
+	if e == nil {
+		return nil, nil
+	}
+	return ParseMappings(e.Value)
+}
+
+// findEntry returns the last entry of the property with the name in the
+// map, matching its aliases as well, or nil if it is not set.
+func findEntry(m *Value, opts []*TypeOption, name string) *Entry {
+	if m.Kind != MapValue {
+		return nil
+	}
+	idx := objectIndex(derefOptions(opts))
+	p := idx[name]
+	var found *Entry
+	for _, e := range mapEntries(m) {
+		if e.Include {
+			continue
+		}
+		if q := idx[strings.ToLower(e.Key)]; (p != nil && q == p) || (p == nil && strings.EqualFold(e.Key, name)) {
+			found = e
+		}
+	}
+	return found
+}
+
+// MapSubject returns the destination subjects a message published to the
+// subject is mapped to on a server in the cluster, along with the mapping
+// matching the subject. It returns nil if no mapping matches.
+func MapSubject(ms []*SubjectMapping, subject, cluster string) (*SubjectMapping, []string, error) {
+	m := MatchMapping(ms, subject)
+	if m == nil {
+		return nil, nil, nil
+	}
+	var subjects []string
+	for _, d := range m.ClusterDestinations(cluster) {
+		s, err := d.Apply(subject)
+		if err != nil {
+			return nil, nil, err
+		}
+		subjects = append(subjects, s)
+	}
+	return m, subjects, nil
+}
+
+// MappingExamples returns the examples of the types of the properties in
+// the config which define tests, e.g. those of the `mappings` type, in
+// the order of the schema. Examples shared by multiple properties are
+// returned once.
+func MappingExamples(cfg *Config) []*Example {
+	var examples []*Example
+	seen := make(map[*Example]bool)
+
+	var walk func(sections []*Section)
+	walk = func(sections []*Section) {
+		for _, s := range sections {
+			for _, p := range s.Properties {
+				for _, o := range p.Types {
+					for _, e := range o.Examples {
+						if len(e.Tests) > 0 && !seen[e] {
+							seen[e] = true
+							examples = append(examples, e)
+						}
+					}
+					if o.Ref == "" {
+						walk(o.Sections)
+					}
+				}
+			}
+		}
+	}
+	walk(cfg.Sections)
+
+	return examples
+}
+
+// RunMappingExample runs the tests of an example of a `mappings` property
+// whose value is a conf snippet defining the mappings, e.g. `mappings {
+// "orders": "orders.internal" }`. It returns a description of each failed
+// test.
+func RunMappingExample(e *Example) ([]string, error) {
+	doc, err := ParseConf(e.Label, []byte(e.Value))
+	if err != nil {
+		return nil, err
+	}
+
+	var v *Value
+	for _, x := range doc.Root.Entries {
+		if !x.Include && x.Value.Kind == MapValue {
+			v = x.Value
+			break
+		}
+	}
+	if v == nil {
+		return nil, fmt.Errorf("example does not define mappings")
+	}
+	ms, err := ParseMappings(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var failures []string
+	for _, t := range e.Tests {
+		name := t.Subject
+		if t.Cluster != "" {
+			name += fmt.Sprintf(" in cluster %q", t.Cluster)
+		}
+		_, got, err := MapSubject(ms, t.Subject, t.Cluster)
+		switch {
+		case err != nil:
+			failures = append(failures, fmt.Sprintf("%s: %s", name, err))
+		case strings.Join(got, " ") != strings.Join(t.Expect, " "):
+			failures = append(failures, fmt.Sprintf("%s: got %s, expected %s", name, codeList(got), codeList(t.Expect)))
+		}
+	}
+	return failures, nil
+}
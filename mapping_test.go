@@ -0,0 +1,79 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMappingExamples(t *testing.T) {
+	cfg, err := DefaultConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	examples := MappingExamples(cfg)
+	if len(examples) == 0 {
+		t.Fatal("no mapping examples with tests found")
+	}
+
+	for _, e := range examples {
+		t.Run(e.Label, func(t *testing.T) {
+			failures, err := RunMappingExample(e)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, f := range failures {
+				t.Error(f)
+			}
+		})
+	}
+}
+
+func TestMapSubject(t *testing.T) {
+	const src = `mappings {
+  "orders.*.*": "orders.{{wildcard(2)}}.{{wildcard(1)}}"
+  "svc": [
+    {destination: svc.west, cluster: west}
+    {destination: svc.default}
+  ]
+}`
+
+	tests := []struct {
+		subject string
+		cluster string
+		want    []string
+		noMatch bool
+	}{
+		{subject: "orders.placed.123", want: []string{"orders.123.placed"}},
+		{subject: "svc", cluster: "west", want: []string{"svc.west"}},
+		{subject: "svc", cluster: "east", want: []string{"svc.default"}},
+		{subject: "svc", want: []string{"svc.default"}},
+		{subject: "other", noMatch: true},
+	}
+
+	doc, err := ParseConf("test.conf", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ms, err := ParseMappings(doc.Root.Entries[0].Value)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		m, got, err := MapSubject(ms, tt.subject, tt.cluster)
+		if err != nil {
+			t.Errorf("MapSubject(%q, %q): %v", tt.subject, tt.cluster, err)
+			continue
+		}
+		if tt.noMatch {
+			if m != nil {
+				t.Errorf("MapSubject(%q) matched %q, want no match", tt.subject, m.Source)
+			}
+			continue
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("MapSubject(%q, %q) = %v, want %v", tt.subject, tt.cluster, got, tt.want)
+		}
+	}
+}
@@ -84,6 +84,15 @@ func generateTemplate(w io.Writer, p *Property, mc *MarkdownConfig, hier []*hier
 	if p.Version != "" {
 		o("- Version introduced: %s\n", p.Version)
 	}
+	if p.DeprecatedIn != "" {
+		o("- Version deprecated: %s\n", p.DeprecatedIn)
+	}
+	if p.RemovedIn != "" {
+		o("- Version removed: %s\n", p.RemovedIn)
+	}
+	if p.ReplacedBy != "" {
+		o("- Replaced by: `%s`\n", p.ReplacedBy)
+	}
 	if len(p.Aliases) > 0 {
 		var aliases []string
 		for _, a := range p.Aliases {
@@ -94,6 +103,27 @@ func generateTemplate(w io.Writer, p *Property, mc *MarkdownConfig, hier []*hier
 
 	o("\n")
 
+	if len(p.Requires) > 0 || len(p.ExclusiveWith) > 0 || len(p.OneOfRequired) > 0 || p.AppliesWhen != nil {
+		o("## Constraints\n\n")
+		if len(p.Requires) > 0 {
+			o("- Requires %s\n", codeList(p.Requires))
+		}
+		if len(p.ExclusiveWith) > 0 {
+			o("- Cannot be set along with %s\n", codeList(p.ExclusiveWith))
+		}
+		if len(p.OneOfRequired) > 0 {
+			o("- This property or one of %s must be set\n", codeList(p.OneOfRequired))
+		}
+		if c := p.AppliesWhen; c != nil {
+			if len(c.Values) > 0 {
+				o("- Only applies when `%s` is %s\n", c.Property, codeList(c.Values))
+			} else {
+				o("- Only applies when `%s` is set\n", c.Property)
+			}
+		}
+		o("\n")
+	}
+
 	if len(p.Types) == 1 && p.Types[0].Type == "object" {
 		renderSections(w, mc, bpath, p.Types[0])
 	} else {
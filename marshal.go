@@ -0,0 +1,282 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// storageUnits are the units used to format storage sizes, from largest
+// to smallest multiplier.
+var storageUnits = []struct {
+	unit string
+	mult int64
+}{
+	{"EB", 1 << 60},
+	{"E", 1000 * 1000 * 1000 * 1000 * 1000 * 1000},
+	{"PB", 1 << 50},
+	{"P", 1000 * 1000 * 1000 * 1000 * 1000},
+	{"TB", 1 << 40},
+	{"T", 1000 * 1000 * 1000 * 1000},
+	{"GB", 1 << 30},
+	{"G", 1000 * 1000 * 1000},
+	{"MB", 1 << 20},
+	{"M", 1000 * 1000},
+	{"KB", 1 << 10},
+	{"K", 1000},
+}
+
+// Marshal renders a struct, such as one generated by GenerateGo, or a
+// map[string]any as a conf file using the schema embedded in this module,
+// see DefaultConfig. The output is formatted by FormatConf, so keys are
+// ordered by the schema.
+func Marshal(v any) ([]byte, error) {
+	cfg, err := DefaultConfig()
+	if err != nil {
+		return nil, err
+	}
+	doc, err := MarshalDocument(cfg, v)
+	if err != nil {
+		return nil, err
+	}
+	return FormatConf(doc, cfg), nil
+}
+
+// MarshalDocument converts a struct or a map[string]any to a conf document,
+// the inverse of UnmarshalDocument. Keys use the canonical property name
+// rather than the alias a field or map key may be named by. Nil pointers,
+// maps, and slices are omitted while other values are written even if
+// they are the zero value, see GenerateGo. Storage sizes are written with
+// units, e.g. `64MB`, durations as strings, e.g. "1m30s", and union
+// structs as their one set field. Map keys which are not properties are
+// sorted.
+func MarshalDocument(cfg *Config, v any) (*Document, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct && rv.Kind() != reflect.Map {
+		return nil, fmt.Errorf("marshal: struct or map required, got %T", v)
+	}
+
+	root, err := encodeValue("", rootOptions(cfg), confTag{}, rv)
+	if err != nil {
+		return nil, err
+	}
+	if root.Kind != MapValue {
+		return nil, fmt.Errorf("marshal: union %T must hold a map", v)
+	}
+	return &Document{Root: root}, nil
+}
+
+// encodeValue converts a Go value to a conf value given the options of
+// the property, if known, and the tag of the struct field. It returns nil
+// for nil values.
+func encodeValue(path string, opts []*TypeOption, tag confTag, rv reflect.Value) (*Value, error) {
+	opts = derefOptions(opts)
+
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		return encodeValue(path, opts, tag, rv.Elem())
+
+	case reflect.Struct:
+		if isUnion(rv.Type()) {
+			return encodeUnion(path, opts, rv)
+		}
+		return encodeStruct(path, opts, rv)
+
+	case reflect.Map:
+		if rv.IsNil() {
+			return nil, nil
+		}
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("marshal %q: unsupported map key type %s", path, rv.Type().Key())
+		}
+
+		keys := make([]string, 0, rv.Len())
+		for _, k := range rv.MapKeys() {
+			keys = append(keys, k.String())
+		}
+		sort.Strings(keys)
+
+		m := &Value{Kind: MapValue}
+		idx := objectIndex(opts)
+		for _, k := range keys {
+			x, err := encodeValue(joinPath(path, k), entryOptions(opts, k), tag, rv.MapIndex(reflect.ValueOf(k).Convert(rv.Type().Key())))
+			if err != nil {
+				return nil, err
+			}
+			if x == nil {
+				continue
+			}
+			// Aliases are written as the canonical property name.
+			if p := idx[strings.ToLower(k)]; p != nil {
+				k = p.Name
+			}
+			m.Entries = append(m.Entries, &Entry{Key: k, Value: x})
+		}
+		return m, nil
+
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return nil, nil
+		}
+		a := &Value{Kind: ArrayValue}
+		inner := unwrapOptions(opts, ArrayValue)
+		for i := 0; i < rv.Len(); i++ {
+			x, err := encodeValue(path, inner, tag, rv.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			if x != nil {
+				a.Elems = append(a.Elems, x)
+			}
+		}
+		return a, nil
+	}
+
+	return encodeScalar(path, opts, tag, rv)
+}
+
+// objectIndex returns the index of the properties of the object option,
+// if any.
+func objectIndex(opts []*TypeOption) map[string]*Property {
+	if len(unwrapOptions(opts, MapValue)) > 0 {
+		return nil
+	}
+	for _, o := range opts {
+		if o.Type == "object" && !isContainer(o) {
+			return propertyIndex(o.Sections)
+		}
+	}
+	return nil
+}
+
+// encodeStruct converts the fields of a struct to the entries of a map.
+func encodeStruct(path string, opts []*TypeOption, rv reflect.Value) (*Value, error) {
+	idx := objectIndex(opts)
+	m := &Value{Kind: MapValue}
+
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag, ok := parseConfTag(f)
+		if !ok || len(tag.names) == 0 {
+			continue
+		}
+
+		key := tag.names[0]
+		var popts []*TypeOption
+		if p := lookupProperty(idx, tag.names); p != nil {
+			key = p.Name
+			popts = p.Types
+		}
+
+		x, err := encodeValue(joinPath(path, key), popts, tag, rv.Field(i))
+		if err != nil {
+			return nil, err
+		}
+		if x != nil {
+			m.Entries = append(m.Entries, &Entry{Key: key, Value: x})
+		}
+	}
+
+	return m, nil
+}
+
+// lookupProperty returns the property named by any of the names.
+func lookupProperty(idx map[string]*Property, names []string) *Property {
+	for _, n := range names {
+		if p := idx[strings.ToLower(n)]; p != nil {
+			return p
+		}
+	}
+	return nil
+}
+
+// encodeUnion converts the set field of a union struct.
+func encodeUnion(path string, opts []*TypeOption, rv reflect.Value) (*Value, error) {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, ok := parseConfTag(f)
+		if !ok || !f.IsExported() || rv.Field(i).IsZero() {
+			continue
+		}
+		return encodeValue(path, unionOptions(opts, tag.typ), tag, rv.Field(i))
+	}
+	return nil, nil
+}
+
+// encodeScalar converts a string, number, boolean, or duration.
+func encodeScalar(path string, opts []*TypeOption, tag confTag, rv reflect.Value) (*Value, error) {
+	if rv.Type() == durationType {
+		s := formatDuration(time.Duration(rv.Int()))
+		return &Value{Kind: StringValue, Raw: strconv.Quote(s), Quote: '"', Str: s}, nil
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		return &Value{Kind: StringValue, Raw: rv.String(), Str: rv.String()}, nil
+
+	case reflect.Bool:
+		return &Value{Kind: BoolValue, Raw: strconv.FormatBool(rv.Bool()), Bool: rv.Bool()}, nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return encodeInteger(rv.Int(), tag.typ == "storage" || hasScalarType(opts, "storage")), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n := rv.Uint()
+		if n > 1<<63-1 {
+			return nil, fmt.Errorf("marshal %q: value %d out of range", path, n)
+		}
+		return encodeInteger(int64(n), tag.typ == "storage" || hasScalarType(opts, "storage")), nil
+
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		raw := strconv.FormatFloat(f, 'f', -1, 64)
+		if !strings.Contains(raw, ".") {
+			raw += ".0"
+		}
+		return &Value{Kind: FloatValue, Raw: raw, Float: f}, nil
+	}
+
+	return nil, fmt.Errorf("marshal %q: unsupported type %s", path, rv.Type())
+}
+
+// encodeInteger converts an integer, written with the largest unit that
+// divides it if it is a storage size, e.g. `64MB`.
+func encodeInteger(n int64, storage bool) *Value {
+	raw := strconv.FormatInt(n, 10)
+	if storage && n != 0 {
+		for _, u := range storageUnits {
+			if n%u.mult == 0 {
+				raw = fmt.Sprintf("%d%s", n/u.mult, u.unit)
+				break
+			}
+		}
+	}
+	return &Value{Kind: IntegerValue, Raw: raw, Int: n}
+}
+
+// formatDuration formats a duration without zero trailing units, e.g.
+// `2m` rather than `2m0s`.
+func formatDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
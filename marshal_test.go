@@ -0,0 +1,103 @@
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMarshal(t *testing.T) {
+	c := testConfig{
+		Port:          int64Ptr(4222),
+		WriteDeadline: durationPtr(90 * time.Second),
+		MaxPayload:    int64Ptr(1 << 20),
+		Tags:          []string{"a", "b"},
+		LeafNodes:     &testLeafNodes{Port: int64Ptr(7422)},
+		JetStream: &testJetStream{
+			MaxMem:       int64Ptr(64 << 20),
+			StoreDir:     stringPtr("/data"),
+			SyncInterval: &testSyncInterval{Choice: stringPtr("always")},
+		},
+	}
+
+	// Keys are canonical and ordered by the schema.
+	const want = `port: 4222
+leafnodes {
+    port: 7422
+}
+jetstream {
+    store_dir: /data
+    max_memory_store: 64MB
+    sync_interval: always
+}
+server_tags: [a, b]
+max_payload: 1MB
+write_deadline: "1m30s"
+`
+
+	got, err := Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("Marshal() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	const src = `port: 4222
+debug: true
+write_deadline: "10s"
+ping_interval: 120
+max_payload: 1MB
+server_tags: [a, b]
+leaf {
+  port: 7422
+}
+jetstream {
+  max_mem: 1G
+  store_dir: "/data"
+  cipher: aes
+  sync: "2m"
+}
+`
+
+	var a testConfig
+	if err := Unmarshal([]byte(src), &a); err != nil {
+		t.Fatal(err)
+	}
+	out, err := Marshal(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b testConfig
+	if err := Unmarshal(out, &b); err != nil {
+		t.Fatalf("marshaled conf does not unmarshal: %v\n%s", err, out)
+	}
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("round trip = %+v, want %+v", b, a)
+	}
+
+	var m map[string]any
+	if err := Unmarshal([]byte(src), &m); err != nil {
+		t.Fatal(err)
+	}
+	out, err = Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Aliases are written as the canonical names, after which the output
+	// is stable.
+	var m2 map[string]any
+	if err := Unmarshal(out, &m2); err != nil {
+		t.Fatalf("marshaled conf does not unmarshal: %v\n%s", err, out)
+	}
+	out2, err := Marshal(m2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, out2) {
+		t.Errorf("round trip =\n%s\nwant\n%s", out2, out)
+	}
+}
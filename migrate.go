@@ -0,0 +1,312 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Migration is a deprecated key found in a conf file.
+type Migration struct {
+	// Pos is the position of the key in the original source.
+	Pos Pos
+
+	// Path is the dotted path of the deprecated property.
+	Path string
+
+	// Property is the deprecated property.
+	Property *Property
+
+	// Applied is true if the key was moved to its replacement.
+	Applied bool
+
+	// Note describes why the key could not be migrated automatically.
+	Note string
+}
+
+// Migrate detects the deprecated keys in the source of a conf file and
+// moves those with a replacement, e.g. the top-level `store_dir` to
+// `store_dir` within the `jetstream` block. The rest of the source is left
+// as is, so it can be compared with the original. A key is moved into the
+// existing block of its replacement, along with the comments directly
+// preceding it, and a block enabled by a value such as `jetstream:
+// enabled` is expanded to hold it. Keys whose replacement block does not
+// exist, is disabled, or already defines the replacement are reported
+// but left in place. The keys of included files are not migrated.
+func Migrate(cfg *Config, filename string, src []byte) ([]byte, []*Migration, error) {
+	doc, err := ParseConf(filename, src)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var migs []*Migration
+	for _, d := range findDeprecated(cfg, doc) {
+		migs = append(migs, &Migration{
+			Pos:      d.entry.KeyPos,
+			Path:     d.path,
+			Property: d.prop,
+		})
+	}
+
+	// Each migration is applied to the source updated by the previous
+	// ones. Keys left in place precede the ones yet to be migrated.
+	skipped := 0
+	for _, m := range migs {
+		doc, err := ParseConf(filename, src)
+		if err != nil {
+			return nil, nil, fmt.Errorf("migrate %s: %w", m.Path, err)
+		}
+		ds := findDeprecated(cfg, doc)
+		if skipped >= len(ds) {
+			break
+		}
+
+		out, note := migrateEntry(cfg, doc, src, ds[skipped])
+		if out == nil {
+			m.Note = note
+			skipped++
+			continue
+		}
+		src = out
+		m.Applied = true
+	}
+
+	return src, migs, nil
+}
+
+// deprecatedEntry is an entry of a deprecated property.
+type deprecatedEntry struct {
+	entry *Entry
+	path  string
+	prop  *Property
+}
+
+// findDeprecated returns the entries of deprecated properties within the
+// objects of the document, in the order they appear.
+func findDeprecated(cfg *Config, doc *Document) []*deprecatedEntry {
+	var ds []*deprecatedEntry
+
+	var walk func(m *Value, path string, opts []*TypeOption)
+	walk = func(m *Value, path string, opts []*TypeOption) {
+		idx := objectIndex(derefOptions(opts))
+		for _, e := range m.Entries {
+			p := idx[strings.ToLower(e.Key)]
+			if e.Include || p == nil {
+				continue
+			}
+			ppath := joinPath(path, p.Name)
+			if p.Deprecation != "" || p.ReplacedBy != "" {
+				ds = append(ds, &deprecatedEntry{entry: e, path: ppath, prop: p})
+				continue
+			}
+			if e.Value.Kind == MapValue {
+				walk(e.Value, ppath, p.Types)
+			}
+		}
+	}
+	walk(doc.Root, "", rootOptions(cfg))
+
+	return ds
+}
+
+// sourceEdit replaces the source between two offsets.
+type sourceEdit struct {
+	start, end int
+	text       string
+}
+
+// migrateEntry moves the entry to its replacement, returning the updated
+// source or nil and the reason it cannot be moved.
+func migrateEntry(cfg *Config, doc *Document, src []byte, d *deprecatedEntry) ([]byte, string) {
+	p := d.prop
+	if p.ReplacedBy == "" {
+		return nil, "no replacement"
+	}
+
+	names := strings.Split(p.ReplacedBy, ".")
+	key := names[len(names)-1]
+
+	// Find the block of the replacement.
+	m, opts := doc.Root, rootOptions(cfg)
+	var parent *Entry
+	for i, name := range names[:len(names)-1] {
+		idx := objectIndex(derefOptions(opts))
+		target := idx[strings.ToLower(name)]
+
+		parent = nil
+		for _, e := range m.Entries {
+			if !e.Include && target != nil && idx[strings.ToLower(e.Key)] == target {
+				parent = e
+			}
+		}
+		if parent == nil {
+			return nil, fmt.Sprintf("%q is not defined", strings.Join(names[:i+1], "."))
+		}
+		if parent.Value.Kind != MapValue && i < len(names)-2 {
+			return nil, fmt.Sprintf("%q is not a block", strings.Join(names[:i+1], "."))
+		}
+		m, opts = parent.Value, target.Types
+	}
+
+	if m.Kind == MapValue {
+		idx := objectIndex(derefOptions(opts))
+		for _, e := range m.Entries {
+			if !e.Include && idx[strings.ToLower(e.Key)] == idx[strings.ToLower(key)] {
+				return nil, fmt.Sprintf("%q is already defined", p.ReplacedBy)
+			}
+		}
+	}
+
+	e := d.entry
+
+	// Comments directly preceding the entry move along with it.
+	var comments []*Comment
+	start := e.KeyPos.Offset
+	if n := len(e.Comments); n > 0 && !e.Comments[n-1].BlankAfter {
+		comments = e.Comments
+		start = e.Comments[0].Pos.Offset
+	}
+
+	lines := make([]string, 0, len(comments)+1)
+	for _, c := range comments {
+		lines = append(lines, c.Text)
+	}
+	lines = append(lines, fmt.Sprintf("%s: %s", key, src[e.Value.Pos.Offset:e.Value.End.Offset]))
+
+	edits := []sourceEdit{removeEntry(src, start, e)}
+
+	switch {
+	case parent == nil:
+		// Top-level replacements are appended to the file.
+		text := strings.Join(lines, "\n") + "\n"
+		if len(src) > 0 && src[len(src)-1] != '\n' {
+			text = "\n" + text
+		}
+		edits = append(edits, sourceEdit{start: len(src), end: len(src), text: text})
+
+	case m.Kind == MapValue:
+		edits = append(edits, insertEntry(src, parent, m, lines))
+
+	case enablesBlock(m):
+		// Expand the value enabling the block, e.g. `jetstream: enabled`.
+		indent := lineIndent(src, parent.KeyPos.Offset)
+		keyEnd := parent.KeyPos.Offset + len(parent.Key)
+		if parent.KeyQuote != 0 {
+			keyEnd += 2
+		}
+		edits = append(edits, sourceEdit{
+			start: keyEnd,
+			end:   m.End.Offset,
+			text:  fmt.Sprintf(" {\n%s\n%s}", strings.Join(prefixLines(indent+strings.Repeat(" ", defaulTabSize), lines), "\n"), indent),
+		})
+
+	default:
+		return nil, fmt.Sprintf("%q is not enabled as a block", strings.Join(names[:len(names)-1], "."))
+	}
+
+	if edits[1].start < edits[0].end && edits[0].start < edits[1].end {
+		return nil, "the replacement overlaps the key"
+	}
+
+	return applyEdits(src, edits), ""
+}
+
+// enablesBlock reports whether the value enables a block, e.g. `true` or
+// `enabled`.
+func enablesBlock(v *Value) bool {
+	switch v.Kind {
+	case BoolValue:
+		return v.Bool
+	case StringValue:
+		return strings.EqualFold(v.Str, "enabled") || strings.EqualFold(v.Str, "enable")
+	}
+	return false
+}
+
+// removeEntry returns the edit removing the entry, starting at the offset,
+// and the rest of its line if it is on its own lines.
+func removeEntry(src []byte, start int, e *Entry) sourceEdit {
+	end := e.Value.End.Offset
+	ls := lineStart(src, start)
+	le := end
+	for le < len(src) && src[le] != '\n' {
+		le++
+	}
+
+	rest := strings.TrimLeft(string(src[end:le]), " \t,;")
+	ownLine := strings.TrimSpace(string(src[ls:start])) == "" &&
+		(rest == "" || strings.HasPrefix(rest, "#") || strings.HasPrefix(rest, "//"))
+	if ownLine {
+		if le < len(src) {
+			le++
+		}
+		// Avoid leaving two empty lines, or one at the start of the file,
+		// in place of the entry.
+		blankBefore := ls == 0 || (ls >= 2 && src[ls-2] == '\n')
+		if blankBefore && le < len(src) && src[le] == '\n' {
+			le++
+		}
+		return sourceEdit{start: ls, end: le}
+	}
+
+	// Remove a separator following the entry within a line.
+	for end < len(src) && (src[end] == ',' || src[end] == ';' || src[end] == ' ') {
+		end++
+	}
+	return sourceEdit{start: start, end: end}
+}
+
+// insertEntry returns the edit inserting the lines of an entry at the end
+// of a block.
+func insertEntry(src []byte, parent *Entry, m *Value, lines []string) sourceEdit {
+	closing := m.End.Offset - 1
+
+	// Within a block on a single line, the entry is appended inline.
+	ls := lineStart(src, closing)
+	if strings.TrimSpace(string(src[ls:closing])) != "" {
+		text := lines[len(lines)-1]
+		if n := len(m.Entries); n > 0 {
+			last := m.Entries[n-1].Value.End.Offset
+			return sourceEdit{start: last, end: last, text: ", " + text}
+		}
+		return sourceEdit{start: closing, end: closing, text: " " + text + " "}
+	}
+
+	var indent string
+	if n := len(m.Entries); n > 0 {
+		indent = lineIndent(src, m.Entries[n-1].KeyPos.Offset)
+	} else if parent != nil {
+		indent = lineIndent(src, parent.KeyPos.Offset) + strings.Repeat(" ", defaulTabSize)
+	}
+	return sourceEdit{start: ls, end: ls, text: strings.Join(prefixLines(indent, lines), "\n") + "\n"}
+}
+
+// applyEdits applies non-overlapping edits to the source.
+func applyEdits(src []byte, edits []sourceEdit) []byte {
+	sort.Slice(edits, func(i, j int) bool {
+		return edits[i].start > edits[j].start
+	})
+	out := append([]byte{}, src...)
+	for _, e := range edits {
+		out = append(out[:e.start], append([]byte(e.text), out[e.end:]...)...)
+	}
+	return out
+}
+
+// lineStart returns the offset of the start of the line of the offset.
+func lineStart(src []byte, offset int) int {
+	for offset > 0 && src[offset-1] != '\n' {
+		offset--
+	}
+	return offset
+}
+
+// lineIndent returns the leading whitespace of the line of the offset.
+func lineIndent(src []byte, offset int) string {
+	ls := lineStart(src, offset)
+	i := ls
+	for i < len(src) && (src[i] == ' ' || src[i] == '\t') {
+		i++
+	}
+	return string(src[ls:i])
+}
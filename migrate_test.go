@@ -0,0 +1,98 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	cfg, err := DefaultConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		src  string
+		want string
+		note string
+	}{
+		{
+			name: "block",
+			src:  "port: 4222\n\n# data\nstore_dir: \"/data\"\n\njetstream {\n  max_mem: 1G\n}\n",
+			want: "port: 4222\n\njetstream {\n  max_mem: 1G\n  # data\n  store_dir: \"/data\"\n}\n",
+		},
+		{
+			name: "enabled",
+			src:  "store_dir: /data\njetstream: enabled\n",
+			want: "jetstream {\n    store_dir: /data\n}\n",
+		},
+		{
+			name: "inline",
+			src:  "jetstream {max_mem: 1G}\nstore_dir: /data\n",
+			want: "jetstream {max_mem: 1G, store_dir: /data}\n",
+		},
+		{
+			name: "empty",
+			src:  "jetstream {\n}\nstore_dir: /data\n",
+			want: "jetstream {\n    store_dir: /data\n}\n",
+		},
+		{
+			name: "undefined",
+			src:  "port: 4222\nstore_dir: /data\n",
+			note: `"jetstream" is not defined`,
+		},
+		{
+			name: "defined",
+			src:  "store_dir: /a\njetstream {\n  store_dir: /b\n}\n",
+			note: `"jetstream.store_dir" is already defined`,
+		},
+		{
+			name: "disabled",
+			src:  "store_dir: /data\njetstream: false\n",
+			note: `"jetstream" is not enabled as a block`,
+		},
+	}
+
+	for _, tt := range tests {
+		out, migs, err := Migrate(cfg, "test.conf", []byte(tt.src))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(migs) != 1 || migs[0].Path != "store_dir" {
+			t.Errorf("%s: migrations = %+v, want store_dir", tt.name, migs)
+			continue
+		}
+
+		m := migs[0]
+		if tt.note != "" {
+			if m.Applied || m.Note != tt.note {
+				t.Errorf("%s: applied %v with note %q, want note %q", tt.name, m.Applied, m.Note, tt.note)
+			}
+			if string(out) != tt.src {
+				t.Errorf("%s: source changed to\n%s", tt.name, out)
+			}
+			continue
+		}
+		if !m.Applied {
+			t.Errorf("%s: not applied: %s", tt.name, m.Note)
+		}
+		if string(out) != tt.want {
+			t.Errorf("%s: Migrate() =\n%s\nwant\n%s", tt.name, out, tt.want)
+		}
+
+		// The output validates without deprecations and is not migrated
+		// again.
+		doc, err := ParseConf("test.conf", out)
+		if err != nil {
+			t.Errorf("%s: migrated conf does not parse: %v", tt.name, err)
+			continue
+		}
+		for _, d := range ValidateDocument(cfg, doc) {
+			t.Errorf("%s: %s", tt.name, d)
+		}
+		if _, migs, _ := Migrate(cfg, "test.conf", out); len(migs) != 0 {
+			t.Errorf("%s: migrated conf has %d deprecated keys", tt.name, len(migs))
+		}
+	}
+}
@@ -81,6 +81,47 @@ type Property struct {
 	// Version indicates the version of the server this property
 	// became available.
 	Version string
+
+	// DeprecatedIn indicates the version of the server this property
+	// was deprecated.
+	DeprecatedIn string
+
+	// RemovedIn indicates the version of the server this property is
+	// no longer supported.
+	RemovedIn string
+
+	// ReplacedBy is the dotted path of the property replacing this
+	// deprecated property, e.g. `jetstream.store_dir`.
+	ReplacedBy string
+
+	// Requires are the names of the properties of the same object that
+	// must be set if this property is set, e.g. `password` for `username`.
+	Requires []string
+
+	// ExclusiveWith are the names of the properties of the same object
+	// that cannot be set along with this property, e.g. `token` for
+	// `username`.
+	ExclusiveWith []string
+
+	// OneOfRequired are the names of the properties of the same object
+	// of which at least one, or this property, must be set, e.g. `service`
+	// for the `stream` of an export.
+	OneOfRequired []string
+
+	// AppliesWhen is the condition on a sibling property for this
+	// property to apply, e.g. `ttl` only applies to a resolver whose
+	// `type` is `cache`. Otherwise the property is ignored by the server.
+	AppliesWhen *Condition
+}
+
+// Condition is a condition on the value of a property of an object.
+type Condition struct {
+	// Property is the name of the property.
+	Property string
+
+	// Values are the values of the property the condition holds for. If
+	// empty, the condition holds if the property is set.
+	Values []string
 }
 
 // Example provides a way to document examples for a property.
@@ -93,6 +134,24 @@ type Example struct {
 
 	// The value, which will be rendered as code.
 	Value string
+
+	// Tests are sample inputs of the example and their expected results,
+	// which make the example runnable, e.g. subjects and the destinations
+	// they are mapped to by a `mappings` example.
+	Tests []*ExampleTest
+}
+
+// ExampleTest is a sample input of an example and its expected result.
+type ExampleTest struct {
+	// Subject is the subject a message is published to.
+	Subject string
+
+	// Cluster is the cluster of the server the message is published to,
+	// if any.
+	Cluster string
+
+	// Expect are the expected destination subjects in order.
+	Expect []string
 }
 
 // TypeOption represents a value type for a property type.
@@ -102,6 +161,11 @@ type TypeOption struct {
 	// Defines the type, whether primitive or an object type.
 	Type string
 
+	// Name is the name of the type definition the option was derived from,
+	// e.g. `jetstream` or `enable-disable`. It is empty for primitive types
+	// and objects defined inline by a property.
+	Name string
+
 	// Denotes the value is an array of other value types.
 	Array bool
 
@@ -120,13 +184,30 @@ type TypeOption struct {
 	// Defines the option is an array of arrays of the specified type.
 	ArrayOfArray bool
 
+	// KeyType is the type of the keys of a map, e.g. `subject` for the
+	// keys of `mappings`. If empty, keys are any string.
+	KeyType string
+
 	// For value types that are enums, this defines the set of choices.
 	Choices []string
 
+	// Examples are the examples of the type definition the option was
+	// derived from, e.g. those of `mappings`. Unlike the examples of a
+	// property, they are not rendered in the docs.
+	Examples []*Example
+
 	// Description of this type option in the context of the parent property.
 	Description string
 
 	// Sections represent the dereferenced sections (of properties) for this
 	// type option. This is only applicable if the type is an object.
 	Sections []*Section
+
+	// Ref is the name of a recursive type this option refers to. Rather
+	// than being expanded again, the definition is that of the enclosing
+	// option of the same type.
+	Ref string
+
+	// Target are the type options of the referenced recursive type.
+	Target []*TypeOption
 }
@@ -2,8 +2,11 @@ package config
 
 import (
 	"fmt"
+	"io/fs"
 	"io/ioutil"
+	"reflect"
 	"regexp"
+	"sort"
 	"strings"
 
 	"gopkg.in/yaml.v3"
@@ -16,13 +19,16 @@ var (
 
 var (
 	primitiveTypes = map[string]string{
-		"boolean":  "",
-		"float":    "",
-		"integer":  "",
-		"string":   "",
-		"duration": "Duration as a string with units such as 100ms, 10s, 5m, or 2h.",
-		"storage":  "Size in bytes or string with a metric unit such as 100K, 50M, 3G, or 1T.",
-		"object":   "An object with a set of explicit properties that can be set.",
+		"boolean":       "",
+		"float":         "",
+		"integer":       "",
+		"string":        "",
+		"subject":       "A subject such as `orders.*` where `*` matches a single token and `>` matches the remaining tokens.",
+		"queue-subject": "A subject optionally followed by a queue group, such as `orders.* workers`.",
+		"transform":     "A destination subject such as `orders.{{wildcard(1)}}` where transform functions refer to the wildcards of the source subject.",
+		"duration":      "Duration as a string with units such as 100ms, 10s, 5m, or 2h.",
+		"storage":       "Size in bytes or string with a metric unit such as 100K, 50M, 3G, or 1T.",
+		"object":        "An object with a set of explicit properties that can be set.",
 	}
 )
 
@@ -37,6 +43,9 @@ type yamlSection struct {
 	Description string
 	URL         string
 	Properties  yaml.Node
+
+	// file is the path of the file the section is defined in.
+	file string
 }
 
 type yamlFile struct {
@@ -59,12 +68,82 @@ type yamlType struct {
 	Sections       []*yamlSection
 	Properties     yaml.Node
 	Version        string
+	DeprecatedIn   string `yaml:"deprecated_in"`
+	RemovedIn      string `yaml:"removed_in"`
+	ReplacedBy     string `yaml:"replaced_by"`
+	Requires       []string
+	ExclusiveWith  []string   `yaml:"exclusive_with"`
+	OneOfRequired  []string   `yaml:"one_of_required"`
+	AppliesWhen    *Condition `yaml:"applies_when"`
+	Keys           string
 	Choices        []string
+	Recursive      bool
+
+	// file is the path of the file the type is defined in.
+	file string
+}
+
+// typeScope tracks the types being dereferenced to detect cycles.
+type typeScope struct {
+	// stack is the chain of types currently being dereferenced.
+	stack []string
+
+	// refs are the reference options created for recursive types which
+	// are resolved once the outermost type has been parsed.
+	refs map[string][]*TypeOption
+}
+
+func newTypeScope() *typeScope {
+	return &typeScope{
+		refs: make(map[string][]*TypeOption),
+	}
+}
+
+// contains reports whether the type is being dereferenced.
+func (ts *typeScope) contains(t string) bool {
+	for _, x := range ts.stack {
+		if x == t {
+			return true
+		}
+	}
+	return false
+}
+
+// chain returns the reference chain leading back to the type, e.g.
+// `account -> account-import -> account`.
+func (ts *typeScope) chain(t string) string {
+	var i int
+	for i = range ts.stack {
+		if ts.stack[i] == t {
+			break
+		}
+	}
+	chain := append(append([]string{}, ts.stack[i:]...), t)
+	return strings.Join(chain, " -> ")
 }
 
 // Parse takes the config and type definition paths and derives the config.
 func Parse(path string, typePaths []string) (*Config, error) {
-	yc, err := loadConfig(path)
+	return parse(ioutil.ReadFile, path, typePaths, true)
+}
+
+// ParseLenient is like Parse but ignores unknown fields rather than
+// returning an error, such as those of an older revision of the config
+// that have since been renamed or removed.
+func ParseLenient(path string, typePaths []string) (*Config, error) {
+	return parse(ioutil.ReadFile, path, typePaths, false)
+}
+
+// ParseFS is like Parse but reads the config and type definitions from
+// the file system, such as an embedded one.
+func ParseFS(fsys fs.FS, path string, typePaths []string) (*Config, error) {
+	return parse(func(name string) ([]byte, error) {
+		return fs.ReadFile(fsys, name)
+	}, path, typePaths, true)
+}
+
+func parse(readFile func(string) ([]byte, error), path string, typePaths []string, strict bool) (*Config, error) {
+	yc, err := loadConfig(readFile, path, strict)
 	if err != nil {
 		return nil, err
 	}
@@ -72,7 +151,7 @@ func Parse(path string, typePaths []string) (*Config, error) {
 	// Load and index the types for reference when parsing.
 	ytypes := make(map[string]*yamlType)
 	for _, path := range typePaths {
-		f, err := loadTypes(path)
+		f, err := loadTypes(readFile, path, strict)
 		if err != nil {
 			return nil, err
 		}
@@ -99,6 +178,7 @@ func Parse(path string, typePaths []string) (*Config, error) {
 
 				t.Sections = []*yamlSection{{
 					Properties: t.Properties,
+					file:       t.file,
 				}}
 
 				t.Properties = yaml.Node{}
@@ -109,7 +189,7 @@ func Parse(path string, typePaths []string) (*Config, error) {
 	}
 
 	// Top-level config sections.
-	sections, err := parseSections(ytypes, yc.Sections)
+	sections, err := parseSections(ytypes, newTypeScope(), yc.Sections)
 	if err != nil {
 		return nil, err
 	}
@@ -120,44 +200,200 @@ func Parse(path string, typePaths []string) (*Config, error) {
 		Sections:    sections,
 	}
 
+	if err := checkNames(c.Sections, ""); err != nil {
+		return nil, err
+	}
+	if err := checkReplacements(c.Sections, c.Sections, ""); err != nil {
+		return nil, err
+	}
+	if err := checkConstraints(c.Sections, ""); err != nil {
+		return nil, err
+	}
+
 	return &c, nil
 }
 
-func loadConfig(path string) (*yamlConfig, error) {
-	b, err := ioutil.ReadFile(path)
+func loadConfig(readFile func(string) ([]byte, error), path string, strict bool) (*yamlConfig, error) {
+	b, err := readFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", path, err)
 	}
 
 	var f yamlConfig
-	err = yaml.Unmarshal(b, &f)
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", path, err)
+	if err := decodeYAML(path, b, &f, strict); err != nil {
+		return nil, err
+	}
+
+	for _, s := range f.Sections {
+		s.file = path
 	}
 
 	return &f, nil
 }
 
-func loadTypes(path string) (*yamlFile, error) {
-	b, err := ioutil.ReadFile(path)
+func loadTypes(readFile func(string) ([]byte, error), path string, strict bool) (*yamlFile, error) {
+	b, err := readFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %w", path, err)
 	}
 
 	var f yamlFile
-	err = yaml.Unmarshal(b, &f)
-	if err != nil {
-		return nil, fmt.Errorf("%s: %w", path, err)
+	if err := decodeYAML(path, b, &f, strict); err != nil {
+		return nil, err
+	}
+
+	for _, t := range f.Types {
+		t.file = path
+		for _, s := range t.Sections {
+			s.file = path
+		}
 	}
 
 	return &f, nil
 }
 
+// decodeYAML decodes the YAML into v. If strict, it returns an error for
+// the first field that is unknown rather than silently dropping it.
+func decodeYAML(path string, b []byte, v any, strict bool) error {
+	if !strict {
+		if err := yaml.Unmarshal(b, v); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		return nil
+	}
+	n, errs := checkYAML(path, b, v)
+	if len(errs) > 0 {
+		return errs[0]
+	}
+	if err := n.Decode(v); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// checkYAML parses the YAML and checks all fields are known to v.
+func checkYAML(path string, b []byte, v any) (*yaml.Node, []error) {
+	var n yaml.Node
+	if err := yaml.Unmarshal(b, &n); err != nil {
+		return nil, []error{fmt.Errorf("%s: %w", path, err)}
+	}
+	return &n, checkFields(path, &n, reflect.TypeOf(v))
+}
+
+var yamlNodeType = reflect.TypeOf(yaml.Node{})
+
+// checkFields returns an error for each mapping key in the node which does
+// not correspond to a field of the type. Raw property nodes are checked as
+// a mapping of property names to type definitions.
+func checkFields(path string, n *yaml.Node, t reflect.Type) []error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) == 0 {
+			return nil
+		}
+		return checkFields(path, n.Content[0], t)
+	case yaml.AliasNode:
+		return checkFields(path, n.Alias, t)
+	}
+
+	if t == yamlNodeType {
+		t = reflect.MapOf(reflect.TypeOf(""), reflect.TypeOf(yamlType{}))
+	}
+
+	var errs []error
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if n.Kind != yaml.MappingNode {
+			return nil
+		}
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, v := n.Content[i], n.Content[i+1]
+			ft, ok := fields[k.Value]
+			if !ok {
+				msg := fmt.Sprintf("unknown field %q", k.Value)
+				if s := suggestField(k.Value, fields); s != "" {
+					msg = fmt.Sprintf("%s, did you mean %q?", msg, s)
+				}
+				errs = append(errs, &ParseError{
+					Pos: Pos{Filename: path, Line: k.Line, Column: k.Column},
+					Msg: msg,
+				})
+				continue
+			}
+			errs = append(errs, checkFields(path, v, ft)...)
+		}
+
+	case reflect.Map:
+		if n.Kind != yaml.MappingNode {
+			return nil
+		}
+		for i := 1; i < len(n.Content); i += 2 {
+			errs = append(errs, checkFields(path, n.Content[i], t.Elem())...)
+		}
+
+	case reflect.Slice:
+		if n.Kind != yaml.SequenceNode {
+			return nil
+		}
+		for _, c := range n.Content {
+			errs = append(errs, checkFields(path, c, t.Elem())...)
+		}
+	}
+
+	return errs
+}
+
+// yamlFields returns the types of the struct fields keyed by the name
+// used when decoding, i.e. the name in the tag or the lowercased field name.
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// suggestField returns the closest known field to the key, if any.
+func suggestField(key string, fields map[string]reflect.Type) string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	best := ""
+	bestDist := len(key)/3 + 1
+	for _, name := range names {
+		if d := editDistance(key, name); d < bestDist {
+			best = name
+			bestDist = d
+		}
+	}
+	return best
+}
+
 // parseSections parses a list of encoded YAML sections.
-func parseSections(ytypes map[string]*yamlType, yss []*yamlSection) ([]*Section, error) {
+func parseSections(ytypes map[string]*yamlType, ts *typeScope, yss []*yamlSection) ([]*Section, error) {
 	sections := make([]*Section, len(yss))
 	for i, ys := range yss {
-		s, err := parseSection(ytypes, ys)
+		s, err := parseSection(ytypes, ts, ys)
 		if err != nil {
 			return nil, err
 		}
@@ -167,7 +403,7 @@ func parseSections(ytypes map[string]*yamlType, yss []*yamlSection) ([]*Section,
 }
 
 // parseSection parses an encoded YAML section.
-func parseSection(ytypes map[string]*yamlType, ys *yamlSection) (*Section, error) {
+func parseSection(ytypes map[string]*yamlType, ts *typeScope, ys *yamlSection) (*Section, error) {
 	// If the section has no properties, it's just a header.
 	if len(ys.Properties.Content) == 0 {
 		return &Section{
@@ -191,16 +427,21 @@ func parseSection(ytypes map[string]*yamlType, ys *yamlSection) (*Section, error
 		kc := ys.Properties.Content[i*2]
 		vc := ys.Properties.Content[i*2+1]
 
-		// Decode the raw property type info.
+		// Decode the raw property type info. Unknown fields have already
+		// been checked when loading the file, unless parsing leniently.
 		var yp yamlType
 		if err := vc.Decode(&yp); err != nil {
-			return nil, fmt.Errorf("failed property decode at line %d: %w", vc.Line, err)
+			return nil, fmt.Errorf("%s: failed property decode at line %d: %w", ys.file, vc.Line, err)
 		}
 
 		yp.Name = kc.Value
+		yp.file = ys.file
+		for _, s := range yp.Sections {
+			s.file = ys.file
+		}
 
 		// Parse the property info to a concrete property.
-		p, err := parseProperty(ytypes, &yp)
+		p, err := parseProperty(ytypes, ts, &yp)
 		if err != nil {
 			return nil, err
 		}
@@ -220,7 +461,7 @@ func parseSection(ytypes map[string]*yamlType, ys *yamlSection) (*Section, error
 // parseProperty recursively builds a property from the raw property info.
 // The provided `type` or `types` dictates how the property is constructed.
 // The simplest case is a single primitive type, e.g. `string`.
-func parseProperty(ytypes map[string]*yamlType, yp *yamlType) (*Property, error) {
+func parseProperty(ytypes map[string]*yamlType, ts *typeScope, yp *yamlType) (*Property, error) {
 	// Normalize.
 	var types []string
 	if yp.Type != "" {
@@ -232,11 +473,27 @@ func parseProperty(ytypes map[string]*yamlType, yp *yamlType) (*Property, error)
 	var opts []*TypeOption
 
 	for _, t := range types {
-		ts, err := parseType(ytypes, t)
+		tos, err := parseType(ytypes, ts, t)
 		if err != nil {
 			return nil, err
 		}
-		opts = append(opts, ts...)
+		opts = append(opts, tos...)
+	}
+
+	if yp.Keys != "" {
+		if _, ok := primitiveTypes[yp.Keys]; !ok || yp.Keys == "object" {
+			return nil, fmt.Errorf("%q: keys must be a primitive type, got %q", yp.Name, yp.Keys)
+		}
+		n := 0
+		for _, o := range opts {
+			if o.Map || o.MapOfArray || o.MapOfMap {
+				o.KeyType = yp.Keys
+				n++
+			}
+		}
+		if n == 0 {
+			return nil, fmt.Errorf("%q: keys set without a map type", yp.Name)
+		}
 	}
 
 	if len(opts) == 1 {
@@ -248,7 +505,7 @@ func parseProperty(ytypes map[string]*yamlType, yp *yamlType) (*Property, error)
 			o.Description = yp.Description
 		}
 		if o.Type == "object" {
-			sections, err := parseSections(ytypes, yp.Sections)
+			sections, err := parseSections(ytypes, ts, yp.Sections)
 			if err != nil {
 				return nil, err
 			}
@@ -261,30 +518,220 @@ func parseProperty(ytypes map[string]*yamlType, yp *yamlType) (*Property, error)
 		}
 	}
 
+	// A property referring to a single type inherits whether it is
+	// disabled, e.g. properties of the `tls` type, as well as whether it is
+	// reloadable and the versions unless they are set on the property itself.
+	disabled := yp.Disabled
+	reloadable, reloadableNote := yp.Reloadable, yp.ReloadableNote
+	version, deprecatedIn, removedIn := yp.Version, yp.DeprecatedIn, yp.RemovedIn
+	if len(types) == 1 {
+		for _, yt := range derivedTypes(ytypes, types[0]) {
+			disabled = disabled || yt.Disabled
+			if reloadable == nil {
+				reloadable = yt.Reloadable
+			}
+			if reloadableNote == "" {
+				reloadableNote = yt.ReloadableNote
+			}
+			if version == "" {
+				version = yt.Version
+			}
+			if deprecatedIn == "" {
+				deprecatedIn = yt.DeprecatedIn
+			}
+			if removedIn == "" {
+				removedIn = yt.RemovedIn
+			}
+		}
+	}
+
 	// Assume properties are reloadable by default.
-	reloadable := true
-	if yp.Reloadable != nil {
-		reloadable = *yp.Reloadable
+	if reloadable == nil {
+		reloadable = new(bool)
+		*reloadable = true
 	}
 
 	p := Property{
 		Name:           strings.TrimSpace(yp.Name),
 		Description:    strings.TrimSpace(yp.Description),
 		Types:          opts,
-		Disabled:       yp.Disabled,
+		Disabled:       disabled,
 		Default:        yp.Default,
 		Deprecation:    strings.TrimSpace(yp.Deprecation),
 		Examples:       yp.Examples,
 		Aliases:        yp.Aliases,
-		Reloadable:     reloadable,
-		ReloadableNote: strings.TrimSpace(yp.ReloadableNote),
+		Reloadable:     *reloadable,
+		ReloadableNote: strings.TrimSpace(reloadableNote),
 		URL:            yp.URL,
+		Version:        version,
+		DeprecatedIn:   deprecatedIn,
+		RemovedIn:      removedIn,
+		ReplacedBy:     strings.TrimSpace(yp.ReplacedBy),
+		Requires:       yp.Requires,
+		ExclusiveWith:  yp.ExclusiveWith,
+		OneOfRequired:  yp.OneOfRequired,
+		AppliesWhen:    yp.AppliesWhen,
 	}
 
 	return &p, nil
 }
 
-func parseType(ytypes map[string]*yamlType, t string) ([]*TypeOption, error) {
+// checkNames checks the names and aliases of the properties of each
+// object are unique. Keys are matched case-insensitively, so two
+// properties differing only by case collide as well.
+func checkNames(sections []*Section, path string) error {
+	seen := make(map[string]*Property)
+	for _, s := range sections {
+		for _, p := range s.Properties {
+			ppath := joinPath(path, p.Name)
+			for i, n := range append([]string{p.Name}, p.Aliases...) {
+				k := strings.ToLower(n)
+				if other := seen[k]; other != nil {
+					what := "name"
+					if i > 0 {
+						what = "alias"
+					}
+					if other == p {
+						return fmt.Errorf("property %q: duplicate %s %q", ppath, what, n)
+					}
+					return fmt.Errorf("property %q: %s %q collides with property %q", ppath, what, n, joinPath(path, other.Name))
+				}
+				seen[k] = p
+			}
+			for _, o := range p.Types {
+				if o.Ref != "" {
+					continue
+				}
+				if err := checkNames(o.Sections, ppath); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkReplacements checks the replacement of each deprecated property
+// refers to an existing property.
+func checkReplacements(root, sections []*Section, path string) error {
+	for _, s := range sections {
+		for _, p := range s.Properties {
+			ppath := joinPath(path, p.Name)
+			if p.ReplacedBy != "" && propertyAtPath(root, p.ReplacedBy) == nil {
+				return fmt.Errorf("property %q: replacement %q not found", ppath, p.ReplacedBy)
+			}
+			for _, o := range p.Types {
+				if o.Ref != "" {
+					continue
+				}
+				if err := checkReplacements(root, o.Sections, ppath); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// checkConstraints checks the properties named by the constraints and
+// conditions of each property are other properties of the same object,
+// and that condition values are among the choices of the property.
+func checkConstraints(sections []*Section, path string) error {
+	idx := propertyIndex(sections)
+	for _, s := range sections {
+		for _, p := range s.Properties {
+			ppath := joinPath(path, p.Name)
+			names := [][]string{p.Requires, p.ExclusiveWith, p.OneOfRequired}
+			if p.AppliesWhen != nil {
+				names = append(names, []string{p.AppliesWhen.Property})
+			}
+			for _, names := range names {
+				for _, n := range names {
+					if q := idx[strings.ToLower(n)]; q == nil || q == p {
+						return fmt.Errorf("property %q: constraint refers to %q which is not another property of the object", ppath, n)
+					}
+				}
+			}
+			if c := p.AppliesWhen; c != nil {
+				if choices := propertyChoices(idx[strings.ToLower(c.Property)]); choices != nil {
+					for _, val := range c.Values {
+						if !containsFold(choices, val) {
+							return fmt.Errorf("property %q: condition value %q is not one of the choices of %q", ppath, val, c.Property)
+						}
+					}
+				}
+			}
+			for _, o := range p.Types {
+				if o.Ref != "" {
+					continue
+				}
+				if err := checkConstraints(o.Sections, ppath); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// propertyChoices returns the choices of the property, or nil if any of
+// its types accepts other values.
+func propertyChoices(p *Property) []string {
+	var choices []string
+	for _, o := range derefOptions(p.Types) {
+		if len(o.Choices) == 0 {
+			return nil
+		}
+		choices = append(choices, o.Choices...)
+	}
+	return choices
+}
+
+// propertyAtPath returns the property at the dotted path of property
+// names or aliases, e.g. `jetstream.store_dir`, or nil if there is none.
+func propertyAtPath(sections []*Section, path string) *Property {
+	var p *Property
+	for _, name := range strings.Split(path, ".") {
+		p = propertyIndex(sections)[strings.ToLower(name)]
+		if p == nil {
+			return nil
+		}
+		sections = nil
+		for _, o := range derefOptions(p.Types) {
+			if o.Type == "object" && !isContainer(o) {
+				sections = o.Sections
+				break
+			}
+		}
+	}
+	return p
+}
+
+// derivedTypes returns the type definition for the type followed by the
+// types it is derived from, e.g. a type defined as another single type.
+func derivedTypes(ytypes map[string]*yamlType, t string) []*yamlType {
+	var yts []*yamlType
+	seen := make(map[string]bool)
+	for !seen[t] {
+		seen[t] = true
+		yt, ok := ytypes[t]
+		if !ok {
+			break
+		}
+		yts = append(yts, yt)
+		if len(yt.Types) != 1 {
+			break
+		}
+		t = yt.Types[0]
+	}
+	return yts
+}
+
+// parseType parses a type, either a primitive or a reference to a type
+// definition, optionally wrapped in an array or map. A type referring back
+// to itself results in an error unless it is marked as recursive, in which
+// case a reference option is returned instead of expanding it again.
+func parseType(ytypes map[string]*yamlType, ts *typeScope, t string) ([]*TypeOption, error) {
 	var (
 		isArray bool
 		isMap   bool
@@ -320,18 +767,64 @@ func parseType(ytypes map[string]*yamlType, t string) ([]*TypeOption, error) {
 		return nil, fmt.Errorf("unknown type %q", t)
 	}
 
-	bp, err := parseProperty(ytypes, b)
+	if ts.contains(t) {
+		if !b.Recursive {
+			return nil, fmt.Errorf("type cycle: %s", ts.chain(t))
+		}
+		x := &TypeOption{
+			Description: strings.TrimSpace(b.Description),
+			Type:        t,
+			Name:        t,
+			Ref:         t,
+			Map:         isMap,
+			Array:       isArray,
+		}
+		ts.refs[t] = append(ts.refs[t], x)
+		return []*TypeOption{x}, nil
+	}
+
+	ts.stack = append(ts.stack, t)
+	bp, err := parseProperty(ytypes, ts, b)
+	ts.stack = ts.stack[:len(ts.stack)-1]
 	if err != nil {
 		return nil, err
 	}
 
+	// Resolve the references to this type made while parsing it.
+	for _, x := range ts.refs[t] {
+		x.Target = bp.Types
+	}
+	delete(ts.refs, t)
+
+	name := t
+
 	var tos []*TypeOption
 	for _, t := range bp.Types {
 		x := &TypeOption{
 			Description: t.Description,
 			Type:        t.Type,
+			Name:        t.Name,
 			Sections:    t.Sections,
 			Choices:     t.Choices,
+			Examples:    t.Examples,
+			Ref:         t.Ref,
+			Target:      t.Target,
+		}
+
+		// The outermost type definition with examples provides them.
+		if len(b.Examples) > 0 {
+			x.Examples = b.Examples
+		}
+
+		// The key type belongs to the map of the referenced type, unless
+		// it is wrapped by another map.
+		if !isMap {
+			x.KeyType = t.KeyType
+		}
+
+		// The innermost type definition names the option.
+		if x.Name == "" {
+			x.Name = name
 		}
 
 		// Wrap with parent type.
@@ -351,7 +844,22 @@ func parseType(ytypes map[string]*yamlType, t string) ([]*TypeOption, error) {
 			} else {
 				x.Array = true
 			}
+		} else {
+			// Retain the container of the referenced type.
+			x.Array = t.Array
+			x.Map = t.Map
+			x.MapOfArray = t.MapOfArray
+			x.ArrayOfMap = t.ArrayOfMap
+			x.MapOfMap = t.MapOfMap
+			x.ArrayOfArray = t.ArrayOfArray
 		}
+
+		// Copies of unresolved references are resolved along with the
+		// original.
+		if x.Ref != "" && x.Target == nil {
+			ts.refs[x.Ref] = append(ts.refs[x.Ref], x)
+		}
+
 		tos = append(tos, x)
 	}
 
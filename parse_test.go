@@ -0,0 +1,80 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// parseTestConfig parses the config and types YAML.
+func parseTestConfig(configYAML, typesYAML string) (*Config, error) {
+	fsys := fstest.MapFS{
+		"config.yaml": {Data: []byte(configYAML)},
+		"types.yaml":  {Data: []byte(typesYAML)},
+	}
+	return ParseFS(fsys, "config.yaml", []string{"types.yaml"})
+}
+
+func TestParseTypeCycle(t *testing.T) {
+	const configYAML = `
+sections:
+  - name: Accounts
+    properties:
+      account:
+        type: account
+`
+	const typesYAML = `
+types:
+  account:
+    type: object
+    properties:
+      imports:
+        type: array(account-import)
+  account-import:
+    type: object
+    properties:
+      account:
+        type: account
+`
+
+	_, err := parseTestConfig(configYAML, typesYAML)
+	if err == nil || !strings.Contains(err.Error(), "type cycle: account -> account-import -> account") {
+		t.Fatalf("got error %v, want type cycle", err)
+	}
+
+	// Marked as recursive, nested references are not expanded.
+	c, err := parseTestConfig(configYAML, strings.Replace(typesYAML, "type: object\n", "type: object\n    recursive: true\n", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	account := c.Sections[0].Properties[0].Types[0]
+	imports := account.Sections[0].Properties[0].Types[0]
+	if !imports.Array || imports.Name != "account-import" {
+		t.Fatalf("imports = %+v, want array(account-import)", imports)
+	}
+	ref := imports.Sections[0].Properties[0].Types[0]
+	if ref.Ref != "account" {
+		t.Fatalf("account of an import = %+v, want a reference to account", ref)
+	}
+	if len(ref.Target) != 1 || ref.Target[0].Sections[0].Properties[0].Name != "imports" {
+		t.Errorf("reference target = %+v, want the account type", ref.Target)
+	}
+}
+
+func TestParseUnknownField(t *testing.T) {
+	const configYAML = `
+sections:
+  - name: Connectivity
+    properties:
+      port:
+        type: integer
+        defualt: 4222
+`
+
+	_, err := parseTestConfig(configYAML, "types: {}\n")
+	const want = `config.yaml:7:9: unknown field "defualt", did you mean "default"?`
+	if err == nil || err.Error() != want {
+		t.Errorf("got error %v, want %s", err, want)
+	}
+}
@@ -0,0 +1,283 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ReloadChange is a key that changed between a running and a proposed
+// conf file, classified by whether the change can be applied by reloading
+// the server, i.e. `nats-server --signal reload`, or requires a restart.
+type ReloadChange struct {
+	Kind ChangeKind
+
+	// Path is the dotted path of the key, e.g. `cluster.port`.
+	Path string
+
+	// Pos is the position of the key in the proposed conf file or, if it
+	// was removed, in the running conf file.
+	Pos Pos
+
+	// Reloadable reports whether the change can be applied with a reload.
+	Reloadable bool
+
+	// Property is the property the reloadability is derived from. This is
+	// either the changed property itself or, for a change nested within a
+	// property that is not reloadable, e.g. `cluster`, that parent. It is
+	// nil for unknown keys.
+	Property *Property
+
+	// Note describes why a restart is required or any caveats of a reload.
+	Note string
+}
+
+// ReloadImpact compares the running and proposed conf files, including
+// the entries of resolved includes, and returns the changed keys,
+// classified as hot-reloadable or requiring a restart.
+// A change within a property that is not reloadable, such as `cluster` or
+// `leafnodes`, requires a restart regardless of the nested property.
+// Unknown keys are assumed to require a restart, except those defining a
+// referenced variable. Variables resolved with ResolveVariables are
+// compared by the value they refer to, so a changed definition is reported
+// on the properties referencing it.
+func ReloadImpact(cfg *Config, running, proposed *Document) []*ReloadChange {
+	r := reloadAnalyzer{
+		vars: make(map[string]bool),
+	}
+	collectVariables(running.Root, r.vars)
+	collectVariables(proposed.Root, r.vars)
+	r.diffMap("", nil, running.Root, proposed.Root, cfg.Sections)
+	return r.changes
+}
+
+type reloadAnalyzer struct {
+	changes []*ReloadChange
+
+	// vars are the variable names referenced in either file. Keys that
+	// are not known properties are skipped if they define one.
+	vars map[string]bool
+}
+
+// add classifies and records a change to the property. The parent is the
+// outermost enclosing property that is not reloadable, if any.
+func (r *reloadAnalyzer) add(kind ChangeKind, path string, pos Pos, p, parent *Property) {
+	c := &ReloadChange{
+		Kind: kind,
+		Path: path,
+		Pos:  pos,
+	}
+
+	switch {
+	case parent != nil:
+		c.Property = parent
+		c.Note = fmt.Sprintf("%q is not reloadable", parent.Name)
+		if parent.ReloadableNote != "" {
+			c.Note += ": " + oneLine(parent.ReloadableNote)
+		}
+	case p == nil:
+		c.Note = "unknown property"
+	default:
+		c.Property = p
+		c.Reloadable = p.Reloadable
+		c.Note = oneLine(p.ReloadableNote)
+	}
+
+	r.changes = append(r.changes, c)
+}
+
+// diffMap compares the entries of two maps against the properties of an
+// object.
+func (r *reloadAnalyzer) diffMap(path string, parent *Property, old, new *Value, sections []*Section) {
+	idx := propertyIndex(sections)
+
+	// Keys are matched case-insensitively and by their canonical name
+	// so renaming a key to one of its aliases is not a change.
+	key := func(e *Entry) (string, *Property) {
+		if p, ok := idx[strings.ToLower(e.Key)]; ok {
+			return p.Name, p
+		}
+		return strings.ToLower(e.Key), nil
+	}
+
+	// Keys defining referenced variables are not properties.
+	skip := func(e *Entry) bool {
+		if e.Include {
+			return true
+		}
+		_, p := key(e)
+		return p == nil && r.vars[e.Key]
+	}
+
+	oldEntries := make(map[string]*Entry)
+	for _, e := range mapEntries(old) {
+		if !skip(e) {
+			k, _ := key(e)
+			oldEntries[k] = e
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, e := range mapEntries(new) {
+		if skip(e) {
+			continue
+		}
+		k, p := key(e)
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+
+		kpath := joinPath(path, k)
+		kparent := reloadParent(parent, p)
+
+		oe, ok := oldEntries[k]
+		if !ok {
+			r.add(PropertyAdded, kpath, e.KeyPos, p, kparent)
+			continue
+		}
+
+		var opts []*TypeOption
+		if p != nil {
+			opts = p.Types
+		}
+		r.diffValue(kpath, e.KeyPos, p, kparent, opts, oe.Value, e.Value)
+	}
+
+	for _, e := range mapEntries(old) {
+		if skip(e) {
+			continue
+		}
+		k, p := key(e)
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		r.add(PropertyRemoved, joinPath(path, k), e.KeyPos, p, reloadParent(parent, p))
+	}
+}
+
+// diffValue compares two values of a property. Maps are compared key by
+// key, either as an object or as a map of values, while other values are
+// compared as a whole. Resolved variables are compared by their values.
+func (r *reloadAnalyzer) diffValue(path string, pos Pos, p, parent *Property, opts []*TypeOption, old, new *Value) {
+	old, new = resolvedValue(old), resolvedValue(new)
+	if valueEqual(old, new) {
+		return
+	}
+
+	opts = derefOptions(opts)
+
+	if old.Kind == MapValue && new.Kind == MapValue {
+		if inner := unwrapOptions(opts, MapValue); len(inner) > 0 {
+			r.diffEntries(path, p, parent, inner, old, new)
+			return
+		}
+		for _, o := range opts {
+			if o.Type == "object" && !isContainer(o) && len(o.Sections) > 0 {
+				r.diffMap(path, reloadParent(parent, p), old, new, o.Sections)
+				return
+			}
+		}
+	}
+
+	r.add(PropertyChanged, path, pos, p, parent)
+}
+
+// diffEntries compares the entries of two maps of values, such as
+// `accounts`, where each entry is a value of the property.
+func (r *reloadAnalyzer) diffEntries(path string, p, parent *Property, opts []*TypeOption, old, new *Value) {
+	oldEntries := make(map[string]*Entry)
+	for _, e := range mapEntries(old) {
+		if !e.Include {
+			oldEntries[e.Key] = e
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, e := range mapEntries(new) {
+		if e.Include || seen[e.Key] {
+			continue
+		}
+		seen[e.Key] = true
+
+		kpath := joinPath(path, e.Key)
+		oe, ok := oldEntries[e.Key]
+		if !ok {
+			r.add(PropertyAdded, kpath, e.KeyPos, p, parent)
+			continue
+		}
+		r.diffValue(kpath, e.KeyPos, p, parent, opts, oe.Value, e.Value)
+	}
+
+	for _, e := range mapEntries(old) {
+		if e.Include || seen[e.Key] {
+			continue
+		}
+		seen[e.Key] = true
+		r.add(PropertyRemoved, joinPath(path, e.Key), e.KeyPos, p, parent)
+	}
+}
+
+// reloadParent returns the outermost property that is not reloadable,
+// given the current one and the property being entered.
+func reloadParent(parent, p *Property) *Property {
+	if parent != nil || p == nil || p.Reloadable {
+		return parent
+	}
+	return p
+}
+
+// valueEqual reports whether two values are the same, regardless of their
+// formatting, e.g. quoting, separators, or the case of keys. Resolved
+// variables are compared by the values they refer to.
+func valueEqual(a, b *Value) bool {
+	a, b = resolvedValue(a), resolvedValue(b)
+	if a.Kind != b.Kind {
+		return false
+	}
+
+	switch a.Kind {
+	case StringValue, VariableValue:
+		return a.Str == b.Str
+	case IntegerValue:
+		return a.Int == b.Int
+	case FloatValue:
+		return a.Float == b.Float
+	case BoolValue:
+		return a.Bool == b.Bool
+	case ArrayValue:
+		if len(a.Elems) != len(b.Elems) {
+			return false
+		}
+		for i := range a.Elems {
+			if !valueEqual(a.Elems[i], b.Elems[i]) {
+				return false
+			}
+		}
+		return true
+	case MapValue:
+		aEntries, bEntries := mapEntries(a), mapEntries(b)
+		if len(aEntries) != len(bEntries) {
+			return false
+		}
+		key := func(e *Entry) string {
+			if e.Include {
+				return "include " + e.Value.Str
+			}
+			return strings.ToLower(e.Key)
+		}
+		entries := make(map[string]*Entry)
+		for _, e := range aEntries {
+			entries[key(e)] = e
+		}
+		for _, e := range bEntries {
+			x, ok := entries[key(e)]
+			if !ok || !valueEqual(x.Value, e.Value) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestReloadImpact(t *testing.T) {
+	cfg, err := DefaultConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const running = `PORT: 4222
+port: $PORT
+debug: false
+leaf {port: 7422}
+cluster {port: 6222, name: a}
+foo: 1
+max_payload: 1MB
+`
+	const proposed = `PORT: 4223
+port: $PORT
+debug: true
+leafnodes {port: 7422}
+cluster {port: 6223, name: a}
+bar: 1
+max_payload: 1048576
+logtime: false
+`
+
+	parse := func(name, src string) *Document {
+		doc, err := ParseConf(name, []byte(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ResolveVariables(doc, &ResolveOptions{Env: map[string]string{}})
+		return doc
+	}
+
+	// Renaming a key to an alias and writing a size without units are not
+	// changes, while a changed variable is reported where it is referenced.
+	want := []string{
+		`proposed.conf:1:1: changed port (reload)`,
+		`proposed.conf:3:1: changed debug (reload)`,
+		`proposed.conf:5:10: changed cluster.port (restart): "cluster" is not reloadable`,
+		`proposed.conf:6:1: added bar (restart): unknown property`,
+		`proposed.conf:8:1: added logtime (reload)`,
+		`running.conf:6:1: removed foo (restart): unknown property`,
+	}
+
+	var got []string
+	for _, c := range ReloadImpact(cfg, parse("running.conf", running), parse("proposed.conf", proposed)) {
+		s := "restart"
+		if c.Reloadable {
+			s = "reload"
+		}
+		s = fmt.Sprintf("%s: %s %s (%s)", c.Pos, c.Kind, c.Path, s)
+		if c.Note != "" {
+			s += ": " + c.Note
+		}
+		got = append(got, s)
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("ReloadImpact() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
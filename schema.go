@@ -0,0 +1,33 @@
+package config
+
+import (
+	"embed"
+	"io/fs"
+	"sync"
+)
+
+// schemaFS contains the config and type definitions of this module.
+//
+//go:embed config.yaml types/*.yaml
+var schemaFS embed.FS
+
+var (
+	defaultOnce   sync.Once
+	defaultConfig *Config
+	defaultErr    error
+)
+
+// DefaultConfig returns the config parsed from the config and type
+// definitions embedded in this module. It is parsed once and shared, so
+// it must not be modified.
+func DefaultConfig() (*Config, error) {
+	defaultOnce.Do(func() {
+		var paths []string
+		paths, defaultErr = fs.Glob(schemaFS, "types/*.yaml")
+		if defaultErr != nil {
+			return
+		}
+		defaultConfig, defaultErr = ParseFS(schemaFS, "config.yaml", paths)
+	})
+	return defaultConfig, defaultErr
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// checkSubject checks the syntax of a subject, such as `orders.*.placed`
+// or `orders.>`. Subjects consist of non-empty tokens separated by `.`,
+// where `*` matches a single token and `>` matches one or more tokens and
+// must be the last token. Subjects cannot contain whitespace. Subject
+// transform functions such as `{{wildcard(1)}}` are treated as part of a
+// token without being checked. An error is returned for invalid subjects
+// and a warning for subjects which are valid, but likely not what was
+// intended, such as the literal token `foo*`.
+func checkSubject(s string) (warn string, err error) {
+	if s == "" {
+		return "", fmt.Errorf("subject is empty")
+	}
+
+	tokens, err := subjectTokens(s)
+	if err != nil {
+		return "", err
+	}
+
+	for i, t := range tokens {
+		switch {
+		case t == "":
+			return "", fmt.Errorf("subject %q has an empty token", s)
+		case strings.ContainsAny(t, " \t\r\n"):
+			return "", fmt.Errorf("subject %q contains whitespace", s)
+		case t == ">" && i < len(tokens)-1:
+			return "", fmt.Errorf("subject %q has `>` before the last token", s)
+		case t == "*" || t == ">":
+		case warn == "" && strings.ContainsAny(t, "*>") && !strings.Contains(t, "{{"):
+			warn = fmt.Sprintf("token %q of subject %q is literal, `*` and `>` are only wildcards as a whole token", t, s)
+		}
+	}
+
+	return warn, nil
+}
+
+// subjectTokens splits a subject into its tokens. The `.` within subject
+// transform functions, e.g. `{{partition(3,1.2)}}`, does not separate
+// tokens and whitespace within them is removed.
+func subjectTokens(s string) ([]string, error) {
+	var (
+		tokens []string
+		b      strings.Builder
+	)
+	for i := 0; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "{{"):
+			end := strings.Index(s[i:], "}}")
+			if end < 0 {
+				return nil, fmt.Errorf("subject %q has an unterminated `{{`", s)
+			}
+			b.WriteString(strings.Join(strings.Fields(s[i:i+end+2]), ""))
+			i += end + 1
+		case s[i] == '.':
+			tokens = append(tokens, b.String())
+			b.Reset()
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return append(tokens, b.String()), nil
+}
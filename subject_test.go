@@ -0,0 +1,88 @@
+package config
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestCheckSubject(t *testing.T) {
+	tests := []struct {
+		subject string
+		valid   bool
+		warn    bool
+	}{
+		{"orders", true, false},
+		{"orders.*.placed", true, false},
+		{"orders.>", true, false},
+		{">", true, false},
+		{"*", true, false},
+		{"a{b", true, false},
+		{"a{", true, false},
+		{"{", true, false},
+		{"a.{.b", true, false},
+		{">{", true, true},
+		{"orders.{{wildcard(1)}}", true, false},
+		{"orders.{{ wildcard(1) }}", true, false},
+		{"orders.{{partition(3,1.2)}}", true, false},
+		{"a{{wildcard(1)}}b", true, false},
+		{"foo*", true, true},
+		{"foo.>bar", true, true},
+		{"", false, false},
+		{"foo..bar", false, false},
+		{".foo", false, false},
+		{"foo.", false, false},
+		{"foo bar", false, false},
+		{"foo.>.bar", false, false},
+		{"a.>.b", false, false},
+		{"orders.{{wildcard(1)", false, false},
+		{"a{{b", false, false},
+	}
+
+	re := regexp.MustCompile(jsonSchemaSubjectPattern)
+
+	for _, tt := range tests {
+		warn, err := checkSubject(tt.subject)
+		if (err == nil) != tt.valid {
+			t.Errorf("checkSubject(%q) error = %v, want valid %v", tt.subject, err, tt.valid)
+		}
+		if (warn != "") != tt.warn {
+			t.Errorf("checkSubject(%q) warning = %q, want warning %v", tt.subject, warn, tt.warn)
+		}
+
+		// The JSON Schema must agree with the validator.
+		if re.MatchString(tt.subject) != tt.valid {
+			t.Errorf("JSON Schema pattern matches %q = %v, want %v", tt.subject, !tt.valid, tt.valid)
+		}
+	}
+}
+
+func TestQueueSubject(t *testing.T) {
+	tests := []struct {
+		value string
+		valid bool
+	}{
+		{"foo", true},
+		{"foo.>", true},
+		{"foo *.dev", true},
+		{"bar v1", true},
+		{" foo  v1 ", true},
+		{"foo..bar", false},
+		{"a.>.b", false},
+		{"a.>.b v1", false},
+		{"a b c", false},
+		{"", false},
+	}
+
+	re := regexp.MustCompile(jsonSchemaQueueSubjectPattern)
+	o := &TypeOption{Type: "queue-subject"}
+
+	for _, tt := range tests {
+		ok, msg := matchScalar(o, &Value{Kind: StringValue, Str: tt.value})
+		if ok != tt.valid {
+			t.Errorf("matchScalar(%q) = %v (%s), want %v", tt.value, ok, msg, tt.valid)
+		}
+		if re.MatchString(tt.value) != tt.valid {
+			t.Errorf("JSON Schema pattern matches %q = %v, want %v", tt.value, !tt.valid, tt.valid)
+		}
+	}
+}
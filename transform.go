@@ -0,0 +1,322 @@
+package config
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// transformFuncRe matches a subject transform function making up a whole
+// token, e.g. `{{wildcard(1)}}` or `{{ partition(3, 1, 2) }}`.
+var transformFuncRe = regexp.MustCompile(`^\{\{\s*([a-zA-Z]+)\s*\((.*)\)\s*\}\}$`)
+
+// transformFuncs are the subject transform functions by lower-cased name
+// along with the number of arguments. The first argument of all functions
+// but partition is a wildcard index.
+var transformFuncs = map[string]struct {
+	name string
+	min  int
+	max  int
+}{
+	"wildcard":       {"wildcard", 1, 1},
+	"partition":      {"partition", 1, -1},
+	"splitfromleft":  {"splitFromLeft", 2, 2},
+	"splitfromright": {"splitFromRight", 2, 2},
+	"slicefromleft":  {"sliceFromLeft", 2, 2},
+	"slicefromright": {"sliceFromRight", 2, 2},
+	"split":          {"split", 2, 2},
+	"left":           {"left", 2, 2},
+	"right":          {"right", 2, 2},
+}
+
+// transform maps subjects matching a source subject to a destination
+// subject, such as `orders.*.*` to `orders.{{wildcard(2)}}.{{wildcard(1)}}`.
+type transform struct {
+	source []string
+	dest   []*transformToken
+
+	// wildcards are the indexes of the `*` tokens of the source.
+	wildcards []int
+}
+
+// transformToken is a token of the destination, either a literal, a
+// function, or `>` for the remaining tokens of the subject.
+type transformToken struct {
+	literal string
+	fn      string
+
+	// wildcard is the 1-based wildcard index the function applies to.
+	wildcard int
+
+	// args are the integer arguments of the function following the
+	// wildcard index, or the wildcard indexes of partition.
+	args []int
+
+	// delim is the delimiter argument of split.
+	delim string
+
+	// rest is true for `>`.
+	rest bool
+}
+
+// parseTransform parses the transform of the source subject to the
+// destination, checking the functions of the destination and that their
+// wildcard indexes refer to a `*` of the source. The legacy `$1` form is
+// equivalent to `{{wildcard(1)}}`.
+func parseTransform(source, dest string) (*transform, error) {
+	if _, err := checkSubject(source); err != nil {
+		return nil, fmt.Errorf("source: %w", err)
+	}
+	if _, err := checkSubject(dest); err != nil {
+		return nil, fmt.Errorf("destination: %w", err)
+	}
+
+	t := &transform{
+		source: strings.Split(source, "."),
+	}
+	for i, tok := range t.source {
+		if tok == "*" {
+			t.wildcards = append(t.wildcards, i)
+		}
+	}
+	hasRest := t.source[len(t.source)-1] == ">"
+
+	tokens, err := subjectTokens(dest)
+	if err != nil {
+		return nil, err
+	}
+
+	checkIndex := func(fn string, n int) error {
+		if n < 1 || n > len(t.wildcards) {
+			if len(t.wildcards) == 1 {
+				return fmt.Errorf("%s: wildcard index %d is out of range, source %q has 1 wildcard", fn, n, source)
+			}
+			return fmt.Errorf("%s: wildcard index %d is out of range, source %q has %d wildcards", fn, n, source, len(t.wildcards))
+		}
+		return nil
+	}
+
+	stars := 0
+	for _, tok := range tokens {
+		switch {
+		case tok == ">":
+			if !hasRest {
+				return nil, fmt.Errorf("destination has `>` but source %q does not", source)
+			}
+			t.dest = append(t.dest, &transformToken{rest: true})
+			continue
+
+		case tok == "*":
+			// Legacy destinations refer to the wildcards in order.
+			stars++
+			if err := checkIndex("*", stars); err != nil {
+				return nil, err
+			}
+			t.dest = append(t.dest, &transformToken{fn: "wildcard", wildcard: stars})
+			continue
+
+		case strings.HasPrefix(tok, "$"):
+			if n, err := strconv.Atoi(tok[1:]); err == nil {
+				if err := checkIndex(tok, n); err != nil {
+					return nil, err
+				}
+				t.dest = append(t.dest, &transformToken{fn: "wildcard", wildcard: n})
+				continue
+			}
+
+		case strings.Contains(tok, "{{"):
+			x, err := parseTransformFunc(tok, checkIndex)
+			if err != nil {
+				return nil, err
+			}
+			t.dest = append(t.dest, x)
+			continue
+		}
+		t.dest = append(t.dest, &transformToken{literal: tok})
+	}
+
+	return t, nil
+}
+
+// parseTransformFunc parses a function token of a destination.
+func parseTransformFunc(tok string, checkIndex func(string, int) error) (*transformToken, error) {
+	m := transformFuncRe.FindStringSubmatch(tok)
+	if m == nil {
+		return nil, fmt.Errorf("invalid transform function %q, functions must be a whole token", tok)
+	}
+	f, ok := transformFuncs[strings.ToLower(m[1])]
+	if !ok {
+		return nil, fmt.Errorf("unknown transform function %q", m[1])
+	}
+
+	var args []string
+	if s := strings.TrimSpace(m[2]); s != "" {
+		args = strings.Split(s, ",")
+		for i, a := range args {
+			args[i] = strings.TrimSpace(a)
+		}
+	}
+	if len(args) < f.min || (f.max >= 0 && len(args) > f.max) {
+		return nil, fmt.Errorf("%s: expected %s, got %d", f.name, describeArgCount(f.min, f.max), len(args))
+	}
+
+	x := &transformToken{fn: f.name}
+	for i, a := range args {
+		if f.name == "split" && i == 1 {
+			x.delim = a
+			if x.delim == "" {
+				return nil, fmt.Errorf("split: delimiter is empty")
+			}
+			continue
+		}
+		n, err := strconv.Atoi(a)
+		if err != nil {
+			return nil, fmt.Errorf("%s: argument %q is not an integer", f.name, a)
+		}
+
+		switch {
+		case f.name == "partition" && i == 0:
+			if n < 1 {
+				return nil, fmt.Errorf("partition: number of partitions must be positive, got %d", n)
+			}
+			x.args = append(x.args, n)
+		case f.name == "partition" || i == 0:
+			if err := checkIndex(f.name, n); err != nil {
+				return nil, err
+			}
+			if i == 0 {
+				x.wildcard = n
+			} else {
+				x.args = append(x.args, n)
+			}
+		default:
+			if n < 1 {
+				return nil, fmt.Errorf("%s: argument %d must be positive", f.name, n)
+			}
+			x.args = append(x.args, n)
+		}
+	}
+	return x, nil
+}
+
+// describeArgCount describes the expected number of arguments.
+func describeArgCount(min, max int) string {
+	switch {
+	case max < 0:
+		return fmt.Sprintf("at least %d arguments", min)
+	case min == 1 && max == 1:
+		return "1 argument"
+	}
+	return fmt.Sprintf("%d arguments", min)
+}
+
+// match returns the tokens of the subject if it matches the source, or
+// nil otherwise.
+func (t *transform) match(subject string) []string {
+	tokens := strings.Split(subject, ".")
+	for i, s := range t.source {
+		switch {
+		case s == ">":
+			if i >= len(tokens) {
+				return nil
+			}
+			return tokens
+		case i >= len(tokens):
+			return nil
+		case s != "*" && s != tokens[i]:
+			return nil
+		}
+	}
+	if len(tokens) != len(t.source) {
+		return nil
+	}
+	return tokens
+}
+
+// apply transforms a subject matching the source.
+func (t *transform) apply(subject string) (string, error) {
+	tokens := t.match(subject)
+	if tokens == nil {
+		return "", fmt.Errorf("subject %q does not match %q", subject, strings.Join(t.source, "."))
+	}
+	wildcard := func(n int) string {
+		return tokens[t.wildcards[n-1]]
+	}
+
+	out := make([]string, 0, len(t.dest))
+	for _, d := range t.dest {
+		switch d.fn {
+		case "":
+			if d.rest {
+				out = append(out, tokens[len(t.source)-1:]...)
+			} else {
+				out = append(out, d.literal)
+			}
+
+		case "wildcard":
+			out = append(out, wildcard(d.wildcard))
+
+		case "partition":
+			var key string
+			if len(d.args) == 1 {
+				key = subject
+			}
+			for _, n := range d.args[1:] {
+				key += wildcard(n)
+			}
+			h := fnv.New32a()
+			h.Write([]byte(key))
+			out = append(out, strconv.Itoa(int(h.Sum32()%uint32(d.args[0]))))
+
+		case "splitFromLeft", "splitFromRight":
+			s, pos := wildcard(d.wildcard), d.args[0]
+			if d.fn == "splitFromRight" {
+				pos = len(s) - pos
+			}
+			if pos > 0 && pos < len(s) {
+				out = append(out, s[:pos], s[pos:])
+			} else {
+				out = append(out, s)
+			}
+
+		case "sliceFromLeft", "sliceFromRight":
+			s, size := wildcard(d.wildcard), d.args[0]
+			first := size
+			if d.fn == "sliceFromRight" && len(s)%size != 0 {
+				first = len(s) % size
+			}
+			for i := 0; i < len(s); {
+				n := size
+				if i == 0 {
+					n = first
+				}
+				if i+n > len(s) {
+					n = len(s) - i
+				}
+				out = append(out, s[i:i+n])
+				i += n
+			}
+
+		case "split":
+			for _, s := range strings.Split(wildcard(d.wildcard), d.delim) {
+				if s != "" {
+					out = append(out, s)
+				}
+			}
+
+		case "left", "right":
+			s, n := wildcard(d.wildcard), d.args[0]
+			if n < len(s) {
+				if d.fn == "left" {
+					s = s[:n]
+				} else {
+					s = s[len(s)-n:]
+				}
+			}
+			out = append(out, s)
+		}
+	}
+	return strings.Join(out, "."), nil
+}
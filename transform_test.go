@@ -0,0 +1,94 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestTransformApply(t *testing.T) {
+	tests := []struct {
+		source  string
+		dest    string
+		subject string
+		want    string
+	}{
+		{"orders.*.*", "orders.{{wildcard(2)}}.{{wildcard(1)}}", "orders.placed.123", "orders.123.placed"},
+		{"orders.*.*", "orders.$2.$1", "orders.placed.123", "orders.123.placed"},
+		{"orders.*.*", "new.*.*", "orders.placed.123", "new.placed.123"},
+		{"a.>", "b.>", "a.x.y", "b.x.y"},
+		{"a.*", "b.{{ wildcard(1) }}", "a.x", "b.x"},
+		{"a.*", "b.{{split(1,-)}}", "a.x-y--z", "b.x.y.z"},
+		{"a.*", "b.{{splitFromLeft(1,2)}}", "a.abcd", "b.ab.cd"},
+		{"a.*", "b.{{splitFromRight(1,1)}}", "a.abcd", "b.abc.d"},
+		{"a.*", "b.{{sliceFromLeft(1,2)}}", "a.abcde", "b.ab.cd.e"},
+		{"a.*", "b.{{sliceFromRight(1,2)}}", "a.abcde", "b.a.bc.de"},
+		{"a.*", "b.{{left(1,2)}}", "a.abcd", "b.ab"},
+		{"a.*", "b.{{right(1,2)}}", "a.abcd", "b.cd"},
+	}
+
+	for _, tt := range tests {
+		tr, err := parseTransform(tt.source, tt.dest)
+		if err != nil {
+			t.Errorf("parseTransform(%q, %q): %v", tt.source, tt.dest, err)
+			continue
+		}
+		got, err := tr.apply(tt.subject)
+		if err != nil {
+			t.Errorf("%q -> %q: apply(%q): %v", tt.source, tt.dest, tt.subject, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q -> %q: apply(%q) = %q, want %q", tt.source, tt.dest, tt.subject, got, tt.want)
+		}
+	}
+}
+
+func TestTransformPartition(t *testing.T) {
+	tr, err := parseTransform("orders.*", "orders.{{partition(3,1)}}.{{wildcard(1)}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Partitioning is deterministic.
+	a, err := tr.apply("orders.123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := tr.apply("orders.123")
+	if a != b {
+		t.Errorf("partition is not deterministic: %q and %q", a, b)
+	}
+	switch a {
+	case "orders.0.123", "orders.1.123", "orders.2.123":
+	default:
+		t.Errorf("apply() = %q, want a partition between 0 and 2", a)
+	}
+}
+
+func TestTransformErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		dest   string
+		want   string
+	}{
+		{"a.*", "b.{{wildcard(2)}}", `wildcard: wildcard index 2 is out of range, source "a.*" has 1 wildcard`},
+		{"a.*.*", "b.$3", `$3: wildcard index 3 is out of range, source "a.*.*" has 2 wildcards`},
+		{"a.*", "b.>", "destination has `>` but source \"a.*\" does not"},
+		{"a.*", "b.{{nope(1)}}", `unknown transform function "nope"`},
+		{"a.*", "b.{{wildcard()}}", `wildcard: expected 1 argument, got 0`},
+		{"a.*", "b.{{left(1)}}", `left: expected 2 arguments, got 1`},
+		{"a.*", "b.{{left(1,x)}}", `left: argument "x" is not an integer`},
+		{"a.*", "b.{{left(1,0)}}", `left: argument 0 must be positive`},
+		{"a.*", "b.{{partition(0,1)}}", `partition: number of partitions must be positive, got 0`},
+		{"a.*", "b.x{{wildcard(1)}}", `invalid transform function "x{{wildcard(1)}}", functions must be a whole token`},
+	}
+
+	for _, tt := range tests {
+		_, err := parseTransform(tt.source, tt.dest)
+		if err == nil {
+			t.Errorf("parseTransform(%q, %q): no error, want %q", tt.source, tt.dest, tt.want)
+			continue
+		}
+		if err.Error() != tt.want {
+			t.Errorf("parseTransform(%q, %q): error %q, want %q", tt.source, tt.dest, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,517 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Unmarshal parses a conf file and decodes it into the value pointed to by
+// v, using the schema embedded in this module, see DefaultConfig. Variables
+// are resolved, falling back to the environment of the process, while
+// include directives cannot be resolved and are an error.
+func Unmarshal(data []byte, v any) error {
+	cfg, err := DefaultConfig()
+	if err != nil {
+		return err
+	}
+
+	doc, err := ParseConf("", data)
+	if err != nil {
+		return err
+	}
+	ResolveVariables(doc, nil)
+
+	return UnmarshalDocument(cfg, doc, v)
+}
+
+// UnmarshalDocument decodes a parsed conf document into the value pointed
+// to by v, which is typically a struct generated by GenerateGo or a
+// map[string]any.
+//
+// Keys are matched to struct fields by the names in the `conf` tag, e.g.
+// `conf:"leafnodes,leaf"`, or the field name, case-insensitively. Keys
+// are also matched by the aliases of the property in the config, so a
+// field tagged with `leafnodes` is set by `leaf`. Keys without a field are
+// ignored. Durations are decoded into time.Duration from strings such as
+// `10s` or from integers as seconds. Storage sizes in strings such as
+// `64MB` are decoded into integers for properties of the `storage` type or
+// fields tagged with `type=storage`, and strings are checked against the
+// choices of the property or tag. Union structs, whose fields are only
+// tagged with a `type=` option, have the field set for the first type the
+// value can be decoded as.
+//
+// Errors are a *ParseError with the position of the offending value.
+// Variables must have been resolved using ResolveVariables and includes
+// using ResolveIncludes.
+func UnmarshalDocument(cfg *Config, doc *Document, v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("unmarshal: non-nil pointer required, got %T", v)
+	}
+	return decodeValue("", doc.Root, rootOptions(cfg), confTag{}, rv.Elem())
+}
+
+// confTag is a parsed `conf` struct tag.
+type confTag struct {
+	// names are the key and aliases of the field.
+	names []string
+
+	// typ is the type of the value, from the `type=` option.
+	typ string
+
+	// choices of a string, from the `choices=` option.
+	choices []string
+}
+
+// parseConfTag parses the tag of a struct field. Without a tag, the field
+// is named by its Go name. The second return value is false if the field
+// is skipped with `conf:"-"`.
+func parseConfTag(f reflect.StructField) (confTag, bool) {
+	s, ok := f.Tag.Lookup("conf")
+	if !ok {
+		return confTag{names: []string{f.Name}}, true
+	}
+	if s == "-" {
+		return confTag{}, false
+	}
+
+	var t confTag
+	for _, x := range strings.Split(s, ",") {
+		switch {
+		case strings.HasPrefix(x, "type="):
+			t.typ = strings.TrimPrefix(x, "type=")
+		case strings.HasPrefix(x, "choices="):
+			t.choices = strings.Split(strings.TrimPrefix(x, "choices="), "|")
+		case x != "":
+			t.names = append(t.names, x)
+		}
+	}
+	return t, true
+}
+
+// unmarshalError returns a positioned error for the value.
+func unmarshalError(val *Value, format string, args ...any) error {
+	return &ParseError{
+		Pos: val.Pos,
+		Msg: fmt.Sprintf(format, args...),
+	}
+}
+
+// typeError returns an error for a value which cannot be decoded into the
+// Go value.
+func typeError(path string, val *Value, rv reflect.Value) error {
+	if path == "" {
+		return unmarshalError(val, "cannot unmarshal %s into %s", describeValue(val), rv.Type())
+	}
+	return unmarshalError(val, "cannot unmarshal %s into %q of type %s", describeValue(val), path, rv.Type())
+}
+
+// decodeValue decodes a conf value into the Go value given the options of
+// the property, if known, and the tag of the struct field.
+func decodeValue(path string, val *Value, opts []*TypeOption, tag confTag, rv reflect.Value) error {
+	if val.Kind == VariableValue {
+		if val.Resolved == nil {
+			return unmarshalError(val, "undefined variable %q", val.Str)
+		}
+		val = val.Resolved
+	}
+
+	opts = derefOptions(opts)
+
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return decodeValue(path, val, opts, tag, rv.Elem())
+
+	case reflect.Interface:
+		if rv.NumMethod() != 0 {
+			return typeError(path, val, rv)
+		}
+		x, err := plainValue(val)
+		if err != nil {
+			return err
+		}
+		if x != nil {
+			rv.Set(reflect.ValueOf(x))
+		}
+		return nil
+
+	case reflect.Struct:
+		if isUnion(rv.Type()) {
+			return decodeUnion(path, val, opts, rv)
+		}
+		if val.Kind != MapValue {
+			return typeError(path, val, rv)
+		}
+		return decodeStruct(path, val, opts, rv)
+
+	case reflect.Map:
+		if val.Kind != MapValue || rv.Type().Key().Kind() != reflect.String {
+			return typeError(path, val, rv)
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.MakeMap(rv.Type()))
+		}
+		for _, e := range mapEntries(val) {
+			if e.Include {
+				return unmarshalError(e.Value, "cannot unmarshal unresolved include directive")
+			}
+			x := reflect.New(rv.Type().Elem()).Elem()
+			if err := decodeValue(joinPath(path, e.Key), e.Value, entryOptions(opts, e.Key), tag, x); err != nil {
+				return err
+			}
+			rv.SetMapIndex(reflect.ValueOf(e.Key).Convert(rv.Type().Key()), x)
+		}
+		return nil
+
+	case reflect.Slice:
+		if val.Kind != ArrayValue {
+			return typeError(path, val, rv)
+		}
+		inner := unwrapOptions(opts, ArrayValue)
+		s := reflect.MakeSlice(rv.Type(), len(val.Elems), len(val.Elems))
+		for i, x := range val.Elems {
+			if err := decodeValue(path, x, inner, tag, s.Index(i)); err != nil {
+				return err
+			}
+		}
+		rv.Set(s)
+		return nil
+	}
+
+	return decodeScalar(path, val, opts, tag, rv)
+}
+
+// decodeStruct decodes the entries of a map into the fields of a struct.
+func decodeStruct(path string, val *Value, opts []*TypeOption, rv reflect.Value) error {
+	var idx map[string]*Property
+	for _, o := range opts {
+		if o.Type == "object" && !isContainer(o) {
+			idx = propertyIndex(o.Sections)
+			break
+		}
+	}
+
+	// Index the fields by their lower-cased names.
+	type field struct {
+		index int
+		tag   confTag
+	}
+	fields := make(map[string]*field)
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag, ok := parseConfTag(f)
+		if !ok {
+			continue
+		}
+		for _, n := range tag.names {
+			fields[strings.ToLower(n)] = &field{index: i, tag: tag}
+		}
+	}
+
+	for _, e := range mapEntries(val) {
+		if e.Include {
+			return unmarshalError(e.Value, "cannot unmarshal unresolved include directive")
+		}
+
+		key := strings.ToLower(e.Key)
+		name := e.Key
+		f := fields[key]
+
+		// The field may be named by the canonical name or another alias.
+		var popts []*TypeOption
+		if p := idx[key]; p != nil {
+			name = p.Name
+			popts = p.Types
+			if f == nil {
+				f = fields[strings.ToLower(p.Name)]
+			}
+			for _, a := range p.Aliases {
+				if f == nil {
+					f = fields[strings.ToLower(a)]
+				}
+			}
+		}
+
+		// Keys without a field, such as variable definitions, are ignored.
+		if f == nil {
+			continue
+		}
+
+		if err := decodeValue(joinPath(path, name), e.Value, popts, f.tag, rv.Field(f.index)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isUnion reports whether the struct is a union, i.e. all fields are
+// only tagged with the type they hold.
+func isUnion(t reflect.Type) bool {
+	n := 0
+	for i := 0; i < t.NumField(); i++ {
+		tag, ok := parseConfTag(t.Field(i))
+		if !ok || !t.Field(i).IsExported() {
+			continue
+		}
+		if len(tag.names) > 0 || tag.typ == "" {
+			return false
+		}
+		n++
+	}
+	return n > 0
+}
+
+// decodeUnion sets the field of the union for the first type the value
+// can be decoded as. Errors within maps and arrays are returned from the
+// first field accepting a map or array, rather than trying the next one.
+func decodeUnion(path string, val *Value, opts []*TypeOption, rv reflect.Value) error {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag, ok := parseConfTag(f)
+		if !ok || !f.IsExported() || !kindAccepts(f.Type, val.Kind) {
+			continue
+		}
+
+		x := reflect.New(f.Type).Elem()
+		err := decodeValue(path, val, unionOptions(opts, tag.typ), tag, x)
+		if err == nil {
+			rv.Set(reflect.Zero(t))
+			rv.Field(i).Set(x)
+			return nil
+		}
+		if val.Kind == MapValue || val.Kind == ArrayValue {
+			return err
+		}
+	}
+
+	if len(opts) > 0 {
+		return unmarshalError(val, "invalid value %s for %q: expected %s", describeValue(val), path, describeOptions(opts))
+	}
+	return typeError(path, val, rv)
+}
+
+// unionOptions returns the options of a union field of the type, given
+// the options of the union.
+func unionOptions(opts []*TypeOption, typ string) []*TypeOption {
+	switch typ {
+	case "array":
+		var x []*TypeOption
+		for _, o := range opts {
+			if o.Array || o.ArrayOfMap || o.ArrayOfArray {
+				x = append(x, o)
+			}
+		}
+		return x
+	case "map":
+		var x []*TypeOption
+		for _, o := range opts {
+			if o.Map || o.MapOfArray || o.MapOfMap {
+				x = append(x, o)
+			}
+		}
+		return x
+	}
+
+	for _, o := range opts {
+		if !isContainer(o) && (o.Name == typ || (o.Name == "" && o.Type == typ)) {
+			return []*TypeOption{o}
+		}
+	}
+
+	// Without the schema, the type of primitives is known.
+	if _, ok := primitiveTypes[typ]; ok {
+		return []*TypeOption{{Type: typ}}
+	}
+	return nil
+}
+
+// kindAccepts reports whether a Go type can hold a value of the kind.
+func kindAccepts(t reflect.Type, kind ValueKind) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Struct:
+		return kind == MapValue || isUnion(t)
+	case reflect.Map:
+		return kind == MapValue
+	case reflect.Slice:
+		return kind == ArrayValue
+	}
+	return kind != MapValue && kind != ArrayValue
+}
+
+// decodeScalar decodes a scalar value into a string, number, boolean, or
+// duration.
+func decodeScalar(path string, val *Value, opts []*TypeOption, tag confTag, rv reflect.Value) error {
+	if val.Kind == MapValue || val.Kind == ArrayValue {
+		return typeError(path, val, rv)
+	}
+
+	storage := tag.typ == "storage" || hasScalarType(opts, "storage")
+
+	choices := tag.choices
+	if len(choices) == 0 {
+		choices = stringChoices(opts)
+	}
+
+	if rv.Type() == durationType {
+		switch val.Kind {
+		case StringValue:
+			d, err := time.ParseDuration(val.Str)
+			if err != nil {
+				return unmarshalError(val, "invalid duration %q for %q", val.Str, path)
+			}
+			rv.SetInt(int64(d))
+			return nil
+		case IntegerValue:
+			// Numbers are interpreted as seconds.
+			rv.SetInt(int64(time.Duration(val.Int) * time.Second))
+			return nil
+		}
+		return typeError(path, val, rv)
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		var s string
+		switch {
+		case val.Kind == StringValue:
+			s = val.Str
+		case len(choices) > 0:
+			// Unquoted values such as `on` may be one of the choices.
+			s = val.Raw
+		default:
+			return typeError(path, val, rv)
+		}
+		if len(choices) > 0 && !containsFold(choices, s) {
+			return unmarshalError(val, "invalid value %q for %q: expected one of %s", s, path, strings.Join(choices, ", "))
+		}
+		rv.SetString(s)
+		return nil
+
+	case reflect.Bool:
+		if val.Kind != BoolValue {
+			return typeError(path, val, rv)
+		}
+		rv.SetBool(val.Bool)
+		return nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var n int64
+		switch {
+		case val.Kind == IntegerValue:
+			n = val.Int
+		case val.Kind == StringValue && storage && storageRe.MatchString(val.Str):
+			x, ok, err := parseInteger(strings.ReplaceAll(val.Str, " ", ""))
+			if !ok || err != nil {
+				return unmarshalError(val, "invalid storage size %q for %q", val.Str, path)
+			}
+			n = x
+		default:
+			return typeError(path, val, rv)
+		}
+
+		if rv.Kind() >= reflect.Uint && rv.Kind() <= reflect.Uint64 {
+			if n < 0 || rv.OverflowUint(uint64(n)) {
+				return unmarshalError(val, "value %d out of range for %q of type %s", n, path, rv.Type())
+			}
+			rv.SetUint(uint64(n))
+			return nil
+		}
+		if rv.OverflowInt(n) {
+			return unmarshalError(val, "value %d out of range for %q of type %s", n, path, rv.Type())
+		}
+		rv.SetInt(n)
+		return nil
+
+	case reflect.Float32, reflect.Float64:
+		switch val.Kind {
+		case FloatValue:
+			rv.SetFloat(val.Float)
+		case IntegerValue:
+			rv.SetFloat(float64(val.Int))
+		default:
+			return typeError(path, val, rv)
+		}
+		return nil
+	}
+
+	return unmarshalError(val, "cannot unmarshal into %q of unsupported type %s", path, rv.Type())
+}
+
+// stringChoices returns the choices of the string options. If any string
+// option accepts any string, there are no choices.
+func stringChoices(opts []*TypeOption) []string {
+	var choices []string
+	for _, o := range opts {
+		if o.Type != "string" || isContainer(o) {
+			continue
+		}
+		if len(o.Choices) == 0 {
+			return nil
+		}
+		choices = append(choices, o.Choices...)
+	}
+	return choices
+}
+
+// plainValue returns the value as a string, int64, float64, bool,
+// []any, or map[string]any.
+func plainValue(val *Value) (any, error) {
+	switch val.Kind {
+	case StringValue:
+		return val.Str, nil
+	case IntegerValue:
+		return val.Int, nil
+	case FloatValue:
+		return val.Float, nil
+	case BoolValue:
+		return val.Bool, nil
+	case VariableValue:
+		if val.Resolved == nil {
+			return nil, unmarshalError(val, "undefined variable %q", val.Str)
+		}
+		return plainValue(val.Resolved)
+	case ArrayValue:
+		a := make([]any, len(val.Elems))
+		for i, x := range val.Elems {
+			v, err := plainValue(x)
+			if err != nil {
+				return nil, err
+			}
+			a[i] = v
+		}
+		return a, nil
+	case MapValue:
+		m := make(map[string]any)
+		for _, e := range mapEntries(val) {
+			if e.Include {
+				return nil, unmarshalError(e.Value, "cannot unmarshal unresolved include directive")
+			}
+			v, err := plainValue(e.Value)
+			if err != nil {
+				return nil, err
+			}
+			m[e.Key] = v
+		}
+		return m, nil
+	}
+	return nil, nil
+}
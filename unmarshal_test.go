@@ -0,0 +1,134 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testSyncInterval struct {
+	Duration *time.Duration `conf:",type=duration"`
+	Choice   *string        `conf:",type=sync-interval-choices,choices=always"`
+}
+
+type testJetStream struct {
+	MaxMem       *int64            `conf:"max_memory_store,max_mem"`
+	StoreDir     *string           `conf:"store_dir"`
+	Cipher       *string           `conf:"cipher"`
+	SyncInterval *testSyncInterval `conf:"sync_interval,sync"`
+}
+
+type testLeafNodes struct {
+	Port *int64 `conf:"port"`
+}
+
+type testConfig struct {
+	Port          *int64         `conf:"port"`
+	Debug         *bool          `conf:"debug"`
+	WriteDeadline *time.Duration `conf:"write_deadline"`
+	PingInterval  *time.Duration `conf:"ping_interval"`
+	MaxPayload    *int64         `conf:"max_payload"`
+	Tags          []string       `conf:"server_tags"`
+	LeafNodes     *testLeafNodes `conf:"leafnodes"`
+	JetStream     *testJetStream `conf:"jetstream"`
+}
+
+func int64Ptr(n int64) *int64                    { return &n }
+func boolPtr(b bool) *bool                       { return &b }
+func stringPtr(s string) *string                 { return &s }
+func durationPtr(d time.Duration) *time.Duration { return &d }
+
+func TestUnmarshal(t *testing.T) {
+	const src = `PORT: 4222
+port: $PORT
+debug: true
+write_deadline: "10s"
+ping_interval: 120
+max_payload: 1MB
+server_tags: [a, b]
+leaf {
+  port: 7422
+}
+jetstream {
+  max_mem: 1G
+  store_dir: "/data"
+  cipher: aes
+  sync: always
+}
+`
+
+	want := testConfig{
+		Port:          int64Ptr(4222),
+		Debug:         boolPtr(true),
+		WriteDeadline: durationPtr(10 * time.Second),
+		PingInterval:  durationPtr(2 * time.Minute),
+		MaxPayload:    int64Ptr(1 << 20),
+		Tags:          []string{"a", "b"},
+		LeafNodes:     &testLeafNodes{Port: int64Ptr(7422)},
+		JetStream: &testJetStream{
+			MaxMem:       int64Ptr(1000 * 1000 * 1000),
+			StoreDir:     stringPtr("/data"),
+			Cipher:       stringPtr("aes"),
+			SyncInterval: &testSyncInterval{Choice: stringPtr("always")},
+		},
+	}
+
+	var got testConfig
+	if err := Unmarshal([]byte(src), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unmarshal() = %+v, want %+v", got, want)
+	}
+
+	// The other type of the union.
+	var js testJetStream
+	if err := Unmarshal([]byte("sync_interval: \"2m\"\n"), &js); err != nil {
+		t.Fatal(err)
+	}
+	if js.SyncInterval == nil || js.SyncInterval.Duration == nil || *js.SyncInterval.Duration != 2*time.Minute {
+		t.Errorf("sync_interval = %+v, want 2m", js.SyncInterval)
+	}
+}
+
+func TestUnmarshalMap(t *testing.T) {
+	const src = "port: 4222\njetstream {max_mem: 1G}\ntags: [a, 1]\n"
+
+	var got map[string]any
+	if err := Unmarshal([]byte(src), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"port":      int64(4222),
+		"jetstream": map[string]any{"max_mem": int64(1000 * 1000 * 1000)},
+		"tags":      []any{"a", int64(1)},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Unmarshal() = %#v, want %#v", got, want)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{src: "port: \"4222\"", want: `1:7: cannot unmarshal`},
+		{src: "write_deadline: \"10 seconds\"", want: `1:17: invalid duration "10 seconds" for "write_deadline"`},
+		{src: "jetstream {\n  cipher: des\n}", want: `2:11: invalid value "des" for "jetstream.cipher": expected one of chacha, chachapoly, aes`},
+		{src: "port: $MISSING_TEST_VARIABLE", want: `1:7: undefined variable "MISSING_TEST_VARIABLE"`},
+		{src: "server_tags: {a: b}", want: `1:14: cannot unmarshal`},
+	}
+
+	for _, tt := range tests {
+		var c testConfig
+		err := Unmarshal([]byte(tt.src), &c)
+		if err == nil {
+			t.Errorf("Unmarshal(%q): no error, want %q", tt.src, tt.want)
+			continue
+		}
+		if got := err.Error(); len(got) < len(tt.want) || got[:len(tt.want)] != tt.want {
+			t.Errorf("Unmarshal(%q): error %q, want prefix %q", tt.src, got, tt.want)
+		}
+	}
+}
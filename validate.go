@@ -0,0 +1,852 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Severity indicates how severe a diagnostic is.
+type Severity int
+
+const (
+	// SeverityError is a problem that will prevent the server from
+	// starting or the value from being applied.
+	SeverityError Severity = iota
+
+	// SeverityWarning is a likely, but not certain, problem.
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	}
+	return "unknown"
+}
+
+// Diagnostic is a problem found when validating a conf file.
+type Diagnostic struct {
+	// Pos is the position of the key or value the diagnostic refers to.
+	Pos Pos
+
+	// Severity of the diagnostic.
+	Severity Severity
+
+	// Path is the dotted path of the property, e.g. `jetstream.max_mem`.
+	// The path uses the canonical property names rather than aliases.
+	Path string
+
+	// Message describing the problem.
+	Message string
+}
+
+// String formats the diagnostic in the style of a compiler message, e.g.
+// `nats.conf:3:1: error: unknown property "max_pyload"`.
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s: %s", d.Pos, d.Severity, d.Message)
+}
+
+// HasErrors reports whether any of the diagnostics is an error.
+func HasErrors(diags []Diagnostic) bool {
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate parses the conf file, resolving its includes and variables, and
+// validates it against the config. Variables fall back to the environment
+// of the process. Read and syntax errors are reported as diagnostics.
+func Validate(cfg *Config, file string) []Diagnostic {
+	doc, err := ParseConfFile(file)
+	if err != nil {
+		return []Diagnostic{errorDiagnostic(file, err)}
+	}
+
+	diags := ResolveIncludes(doc)
+	diags = append(diags, ResolveVariables(doc, nil)...)
+	return append(diags, ValidateDocument(cfg, doc)...)
+}
+
+// ValidateDocument validates a parsed conf file against the config. The
+// entries of resolved includes are validated as part of the including map
+// and diagnostics refer to the positions in the included files. Variables
+// are validated by the value they refer to if they have been resolved with
+// ResolveVariables, otherwise they are skipped.
+func ValidateDocument(cfg *Config, doc *Document) []Diagnostic {
+	v := validator{
+		vars: make(map[string]bool),
+	}
+	collectVariables(doc.Root, v.vars)
+	v.validateMap("", doc.Root, cfg.Sections)
+	return v.diags
+}
+
+// errorDiagnostic converts an error from reading or parsing a file into
+// a diagnostic.
+func errorDiagnostic(file string, err error) Diagnostic {
+	if pe, ok := err.(*ParseError); ok {
+		return Diagnostic{
+			Pos:      pe.Pos,
+			Severity: SeverityError,
+			Message:  pe.Msg,
+		}
+	}
+	return Diagnostic{
+		Pos:      Pos{Filename: file},
+		Severity: SeverityError,
+		Message:  err.Error(),
+	}
+}
+
+// collectVariables indexes the names of all referenced variables.
+func collectVariables(v *Value, vars map[string]bool) {
+	switch v.Kind {
+	case VariableValue:
+		vars[v.Str] = true
+	case MapValue:
+		for _, e := range mapEntries(v) {
+			collectVariables(e.Value, vars)
+		}
+	case ArrayValue:
+		for _, x := range v.Elems {
+			collectVariables(x, vars)
+		}
+	}
+}
+
+type validator struct {
+	diags []Diagnostic
+
+	// vars are the referenced variable names. Keys that are not known
+	// properties are allowed if they define a referenced variable.
+	vars map[string]bool
+
+	// source is the key of the enclosing map entry if the keys of the map
+	// are subjects, i.e. the source subject of transforms.
+	source string
+}
+
+func (v *validator) add(pos Pos, sev Severity, path string, format string, args ...any) {
+	v.diags = append(v.diags, Diagnostic{
+		Pos:      pos,
+		Severity: sev,
+		Path:     path,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// propertyIndex indexes the properties in the sections by lower-cased
+// name and alias since the server matches keys case-insensitively.
+func propertyIndex(sections []*Section) map[string]*Property {
+	idx := make(map[string]*Property)
+	for _, s := range sections {
+		for _, p := range s.Properties {
+			idx[strings.ToLower(p.Name)] = p
+			for _, a := range p.Aliases {
+				idx[strings.ToLower(a)] = p
+			}
+		}
+	}
+	return idx
+}
+
+// validateMap validates the entries of a map against the properties
+// of an object.
+func (v *validator) validateMap(path string, m *Value, sections []*Section) {
+	idx := propertyIndex(sections)
+
+	// The first entry of each property, to detect a property being set
+	// by both its name and an alias.
+	set := make(map[*Property]*Entry)
+
+	for _, e := range mapEntries(m) {
+		if e.Include {
+			continue
+		}
+
+		p, ok := idx[strings.ToLower(e.Key)]
+		if !ok {
+			// Keys defining referenced variables are not properties.
+			if v.vars[e.Key] {
+				continue
+			}
+			msg := fmt.Sprintf("unknown property %q", e.Key)
+			if s := suggestProperty(e.Key, idx); s != "" {
+				msg += fmt.Sprintf(", did you mean %q?", s)
+			}
+			v.add(e.KeyPos, SeverityError, joinPath(path, e.Key), "%s", msg)
+			continue
+		}
+
+		ppath := joinPath(path, p.Name)
+		if first := set[p]; first == nil {
+			set[p] = e
+		} else if !strings.EqualFold(first.Key, e.Key) {
+			v.add(e.KeyPos, SeverityError, ppath, "%q conflicts with %q at %s: both set property %q", e.Key, first.Key, first.KeyPos, ppath)
+		}
+
+		switch {
+		case p.ReplacedBy != "":
+			v.add(e.KeyPos, SeverityWarning, ppath, "property %q is deprecated, use %q instead: %s", e.Key, p.ReplacedBy, oneLine(p.Deprecation))
+		case p.Deprecation != "":
+			v.add(e.KeyPos, SeverityWarning, ppath, "property %q is deprecated: %s", e.Key, oneLine(p.Deprecation))
+		}
+
+		v.validateValue(ppath, p.Types, e.Value)
+	}
+
+	v.validateConstraints(path, m, sections, idx, set)
+}
+
+// validateConstraints checks the properties set in a map, by their first
+// entry, satisfy the requires, exclusive_with, and one_of_required
+// constraints of the properties of the object.
+func (v *validator) validateConstraints(path string, m *Value, sections []*Section, idx map[string]*Property, set map[*Property]*Entry) {
+	// Exclusive properties are reported once, at the entry set last.
+	order := make(map[*Entry]int)
+	for i, e := range mapEntries(m) {
+		order[e] = i
+	}
+	reported := make(map[string]bool)
+
+	for _, s := range sections {
+		for _, p := range s.Properties {
+			e := set[p]
+			ppath := joinPath(path, p.Name)
+
+			if len(p.OneOfRequired) > 0 && e == nil {
+				names := []string{p.Name}
+				found := false
+				for _, n := range p.OneOfRequired {
+					q := idx[strings.ToLower(n)]
+					found = found || set[q] != nil
+					names = append(names, q.Name)
+				}
+				key := sortedKey(names)
+				if !found && !reported[key] {
+					reported[key] = true
+					v.add(m.Pos, SeverityError, path, "one of %s is required", quoteList(names, "or"))
+				}
+			}
+
+			if e == nil {
+				continue
+			}
+
+			if c := p.AppliesWhen; c != nil {
+				if msg := checkCondition(c, idx, set); msg != "" {
+					v.add(e.KeyPos, SeverityWarning, ppath, "%q is ignored %s", e.Key, msg)
+				}
+			}
+
+			for _, n := range p.Requires {
+				if q := idx[strings.ToLower(n)]; set[q] == nil {
+					v.add(e.KeyPos, SeverityError, ppath, "property %q requires %q", e.Key, q.Name)
+				}
+			}
+
+			for _, n := range p.ExclusiveWith {
+				q := idx[strings.ToLower(n)]
+				f := set[q]
+				if f == nil {
+					continue
+				}
+				key := sortedKey([]string{p.Name, q.Name})
+				if reported[key] {
+					continue
+				}
+				reported[key] = true
+				later, earlier := e, f
+				if order[f] > order[e] {
+					later, earlier = f, e
+				}
+				v.add(later.KeyPos, SeverityError, joinPath(path, idx[strings.ToLower(later.Key)].Name), "%q cannot be set along with %q at %s", later.Key, earlier.Key, earlier.KeyPos)
+			}
+		}
+	}
+}
+
+// checkCondition checks the condition holds for the properties set in a
+// map, or their defaults. It returns a description of why it does not
+// hold, e.g. `when "type" is "full", it only applies when "type" is
+// "cache"`, or the empty string if it holds.
+func checkCondition(c *Condition, idx map[string]*Property, set map[*Property]*Entry) string {
+	q := idx[strings.ToLower(c.Property)]
+	want := quoteList(c.Values, "or")
+
+	var (
+		value string
+		ok    bool
+	)
+	if f := set[q]; f != nil {
+		val := f.Value
+		if val.Kind == VariableValue {
+			if val.Resolved == nil {
+				return ""
+			}
+			val = val.Resolved
+		}
+		if len(c.Values) == 0 {
+			return ""
+		}
+		switch val.Kind {
+		case StringValue:
+			value, ok = val.Str, true
+		case MapValue, ArrayValue:
+		default:
+			value, ok = val.Raw, true
+		}
+	} else if q.Default != nil {
+		value, ok = fmt.Sprint(q.Default), true
+	}
+
+	if len(c.Values) == 0 {
+		return fmt.Sprintf("unless %q is set", q.Name)
+	}
+	if !ok {
+		return fmt.Sprintf("unless %q is %s", q.Name, want)
+	}
+	if containsFold(c.Values, value) {
+		return ""
+	}
+	return fmt.Sprintf("when %q is %q, it only applies when %q is %s", q.Name, value, q.Name, want)
+}
+
+// sortedKey returns a key identifying the set of names.
+func sortedKey(names []string) string {
+	sorted := append([]string{}, names...)
+	sort.Strings(sorted)
+	return strings.Join(sorted, ",")
+}
+
+// quoteList quotes the names and joins them with the conjunction, e.g.
+// `"stream" or "service"`.
+func quoteList(names []string, conj string) string {
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = strconv.Quote(n)
+	}
+	if len(quoted) <= 1 {
+		return strings.Join(quoted, "")
+	}
+	return strings.Join(quoted[:len(quoted)-1], ", ") + " " + conj + " " + quoted[len(quoted)-1]
+}
+
+// validateValue validates a value against a set of type options. Container
+// values are validated by unwrapping one level of the options, e.g. the
+// elements of an array are validated against the `array(T)` options, so
+// properties such as `mappings`, whose map values can either be a string
+// or an array, are supported.
+func (v *validator) validateValue(path string, opts []*TypeOption, val *Value) {
+	if val.Kind == VariableValue {
+		if val.Resolved == nil {
+			return
+		}
+		// Problems with a scalar are reported at the reference.
+		x := *val.Resolved
+		if x.Kind != MapValue && x.Kind != ArrayValue {
+			x.Pos = val.Pos
+		}
+		val = &x
+	}
+
+	opts = derefOptions(opts)
+
+	switch val.Kind {
+	case ArrayValue:
+		if inner := unwrapOptions(opts, ArrayValue); len(inner) > 0 {
+			for _, x := range val.Elems {
+				v.validateValue(path, inner, x)
+			}
+			return
+		}
+
+	case MapValue:
+		if inner := unwrapOptions(opts, MapValue); len(inner) > 0 {
+			keyType := mapKeyType(opts)
+			for _, e := range mapEntries(val) {
+				if e.Include {
+					continue
+				}
+				if keyType != "" {
+					v.validateKey(path, keyType, e)
+				}
+				source := v.source
+				if keyType == "subject" {
+					v.source = e.Key
+				}
+				v.validateValue(joinPath(path, e.Key), inner, e.Value)
+				v.source = source
+				if isMapping(inner) {
+					v.validateWeights(joinPath(path, e.Key), e.Key, e.Value)
+				}
+			}
+			return
+		}
+		for _, o := range opts {
+			if o.Type == "object" && !isContainer(o) {
+				// Objects without properties are free-form.
+				if len(o.Sections) > 0 {
+					v.validateMap(path, val, o.Sections)
+				}
+				return
+			}
+		}
+
+	default:
+		var reason string
+		for _, o := range opts {
+			if isContainer(o) {
+				continue
+			}
+			ok, msg := matchScalar(o, val)
+			if ok && o.Type == "transform" && v.source != "" {
+				dest, _ := mappingString(val)
+				if _, err := parseTransform(v.source, dest); err != nil {
+					ok, msg = false, err.Error()
+				}
+			}
+			if !ok {
+				if reason == "" {
+					reason = msg
+				}
+				continue
+			}
+			if msg != "" {
+				v.add(val.Pos, SeverityWarning, path, "%s", msg)
+			}
+			return
+		}
+		if reason != "" {
+			v.add(val.Pos, SeverityError, path, "invalid value %s for %q: %s", describeValue(val), path, reason)
+			return
+		}
+	}
+
+	v.add(val.Pos, SeverityError, path, "invalid value %s for %q: expected %s", describeValue(val), path, describeOptions(opts))
+}
+
+// validateKey validates the key of a map entry against the key type of
+// the map.
+func (v *validator) validateKey(path, keyType string, e *Entry) {
+	key := &Value{Kind: StringValue, Pos: e.KeyPos, Raw: e.Key, Str: e.Key}
+	ok, msg := matchScalar(&TypeOption{Type: keyType}, key)
+	switch {
+	case !ok && msg != "":
+		v.add(e.KeyPos, SeverityError, joinPath(path, e.Key), "invalid key %q for %q: %s", e.Key, path, msg)
+	case !ok:
+		v.add(e.KeyPos, SeverityError, joinPath(path, e.Key), "invalid key %q for %q: expected %s", e.Key, path, keyType)
+	case msg != "":
+		v.add(e.KeyPos, SeverityWarning, joinPath(path, e.Key), "%s", msg)
+	}
+}
+
+// validateWeights checks the weights of the destinations of a subject
+// mapping, grouped by the cluster they are scoped to, add up to 100%. Less
+// is a warning since the remaining messages are dropped, which may be
+// intended for testing, while more is an error.
+func (v *validator) validateWeights(path, source string, val *Value) {
+	val = resolvedValue(val)
+	if val.Kind != ArrayValue {
+		return
+	}
+
+	var (
+		clusters []string
+		groups   = make(map[string][]*MappingDestination)
+
+		// invalid are the clusters with an invalid destination, whose
+		// weights are not summed. If the cluster of an invalid
+		// destination is unknown, none are summed.
+		invalid    = make(map[string]bool)
+		invalidAll bool
+	)
+	for _, x := range val.Elems {
+		x = resolvedValue(x)
+		if x.Kind != MapValue {
+			continue
+		}
+		d, err := parseMappingDestination(x)
+		if err != nil {
+			if pe, ok := err.(*ParseError); ok {
+				v.add(pe.Pos, SeverityError, path, "%s", pe.Msg)
+			}
+			if c, ok := destinationCluster(x); ok {
+				invalid[c] = true
+			} else {
+				invalidAll = true
+			}
+			continue
+		}
+		if _, ok := groups[d.Cluster]; !ok {
+			clusters = append(clusters, d.Cluster)
+		}
+		groups[d.Cluster] = append(groups[d.Cluster], d)
+	}
+	if invalidAll {
+		return
+	}
+
+	for _, c := range clusters {
+		if invalid[c] {
+			continue
+		}
+		ds := groups[c]
+		total := 0
+		dests := make([]string, len(ds))
+		for i, d := range ds {
+			total += d.Weight
+			dests[i] = fmt.Sprintf("%q (%d%%)", d.Subject, d.Weight)
+		}
+		if total == 100 {
+			continue
+		}
+
+		scope := "not scoped to a cluster"
+		if c != "" {
+			scope = fmt.Sprintf("in cluster %q", c)
+		}
+		if total > 100 {
+			v.add(ds[0].Pos, SeverityError, path, "weights of the destinations of %q %s add up to %d%%, more than 100%%: %s", source, scope, total, strings.Join(dests, ", "))
+		} else {
+			v.add(ds[0].Pos, SeverityWarning, path, "weights of the destinations of %q %s add up to %d%%, the remaining %d%% of messages are dropped: %s", source, scope, total, 100-total, strings.Join(dests, ", "))
+		}
+	}
+}
+
+// destinationCluster returns the cluster a destination map is scoped to,
+// or false if it is not a valid cluster name.
+func destinationCluster(v *Value) (string, bool) {
+	var cluster string
+	for _, e := range mapEntries(v) {
+		if !e.Include && strings.EqualFold(e.Key, "cluster") {
+			s, ok := mappingString(resolvedValue(e.Value))
+			if !ok {
+				return "", false
+			}
+			cluster = s
+		}
+	}
+	return cluster, true
+}
+
+// isContainer reports whether the type option is an array or map of
+// the type.
+func isContainer(o *TypeOption) bool {
+	return o.Array || o.Map || o.MapOfArray || o.ArrayOfMap || o.MapOfMap || o.ArrayOfArray
+}
+
+// derefOptions replaces references to recursive types with the options
+// of the referenced type. References within containers are replaced once
+// they have been unwrapped.
+func derefOptions(opts []*TypeOption) []*TypeOption {
+	var x []*TypeOption
+	for _, o := range opts {
+		if o.Ref == "" || isContainer(o) {
+			x = append(x, o)
+			continue
+		}
+		x = append(x, o.Target...)
+	}
+	return x
+}
+
+// isMapping reports whether the options are those of the value of a
+// subject mapping, i.e. they include the `mapping-destination` type.
+func isMapping(opts []*TypeOption) bool {
+	for _, o := range opts {
+		if o.Name == "mapping-destination" {
+			return true
+		}
+	}
+	return false
+}
+
+// mapKeyType returns the key type of the map options, if any.
+func mapKeyType(opts []*TypeOption) string {
+	for _, o := range opts {
+		if (o.Map || o.MapOfArray || o.MapOfMap) && o.KeyType != "" {
+			return o.KeyType
+		}
+	}
+	return ""
+}
+
+// unwrapOptions returns the options whose outermost container is of the
+// given kind with that container removed.
+func unwrapOptions(opts []*TypeOption, kind ValueKind) []*TypeOption {
+	var inner []*TypeOption
+	for _, o := range opts {
+		x := *o
+		x.Array, x.Map, x.MapOfArray, x.ArrayOfMap, x.MapOfMap, x.ArrayOfArray = false, false, false, false, false, false
+		if kind == MapValue {
+			x.KeyType = ""
+		}
+
+		switch {
+		case kind == ArrayValue && o.Array:
+		case kind == ArrayValue && o.ArrayOfMap:
+			x.Map = true
+		case kind == ArrayValue && o.ArrayOfArray:
+			x.Array = true
+		case kind == MapValue && o.Map:
+		case kind == MapValue && o.MapOfArray:
+			x.Array = true
+		case kind == MapValue && o.MapOfMap:
+			x.Map = true
+		default:
+			continue
+		}
+		inner = append(inner, &x)
+	}
+	return inner
+}
+
+var storageRe = regexp.MustCompile(`^[0-9]+\s*[a-zA-Z]*$`)
+
+// matchScalar reports whether a scalar value matches a non-container type
+// option. A warning is returned for values that match, but are likely
+// not what was intended, and the reason for values of the type that are
+// invalid, such as a subject with an empty token.
+func matchScalar(o *TypeOption, val *Value) (bool, string) {
+	switch o.Type {
+	case "string":
+		var s string
+		switch val.Kind {
+		case StringValue:
+			s = val.Str
+		case BoolValue, IntegerValue, FloatValue:
+			// Unquoted values such as `on` may be one of the choices.
+			if len(o.Choices) == 0 {
+				return false, ""
+			}
+			s = val.Raw
+		default:
+			return false, ""
+		}
+		if len(o.Choices) > 0 && !containsFold(o.Choices, s) {
+			return false, ""
+		}
+		return true, ""
+
+	case "integer":
+		return val.Kind == IntegerValue, ""
+
+	case "float":
+		return val.Kind == FloatValue || val.Kind == IntegerValue, ""
+
+	case "boolean":
+		return val.Kind == BoolValue, ""
+
+	case "duration":
+		switch val.Kind {
+		case StringValue:
+			_, err := time.ParseDuration(val.Str)
+			return err == nil, ""
+		case IntegerValue:
+			if val.Raw != fmt.Sprint(val.Int) {
+				return true, fmt.Sprintf("unquoted %s is parsed as the number %d which is interpreted as seconds, quote the value to use a duration", val.Raw, val.Int)
+			}
+			return true, fmt.Sprintf("number %d is interpreted as seconds, use a duration such as \"%ds\" instead", val.Int, val.Int)
+		}
+		return false, ""
+
+	case "storage":
+		switch val.Kind {
+		case IntegerValue:
+			return true, ""
+		case StringValue:
+			if !storageRe.MatchString(val.Str) {
+				return false, ""
+			}
+			_, ok, err := parseInteger(strings.ReplaceAll(val.Str, " ", ""))
+			return ok && err == nil, ""
+		}
+		return false, ""
+
+	case "subject", "transform":
+		var s string
+		switch val.Kind {
+		case StringValue:
+			s = val.Str
+		case IntegerValue, FloatValue, BoolValue:
+			s = val.Raw
+		default:
+			return false, ""
+		}
+		warn, err := checkSubject(s)
+		if err != nil {
+			return false, err.Error()
+		}
+		return true, warn
+
+	case "queue-subject":
+		if val.Kind != StringValue {
+			return matchScalar(&TypeOption{Type: "subject"}, val)
+		}
+		// The queue group is separated from the subject by whitespace.
+		fields := strings.Fields(val.Str)
+		switch len(fields) {
+		case 0:
+			return false, "subject is empty"
+		case 1, 2:
+		default:
+			return false, fmt.Sprintf("%q is not a subject optionally followed by a queue group", val.Str)
+		}
+		warn, err := checkSubject(fields[0])
+		if err != nil {
+			return false, err.Error()
+		}
+		return true, warn
+
+	case "object":
+		return false, ""
+	}
+
+	// Unknown types are not validated.
+	return true, ""
+}
+
+func containsFold(choices []string, s string) bool {
+	for _, c := range choices {
+		if strings.EqualFold(c, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// describeValue describes a value for diagnostic messages.
+func describeValue(val *Value) string {
+	switch val.Kind {
+	case MapValue, ArrayValue:
+		return val.Kind.String()
+	}
+	raw := val.Raw
+	if len(raw) > 40 {
+		raw = raw[:37] + "..."
+	}
+	return fmt.Sprintf("%s (%s)", raw, val.Kind)
+}
+
+// describeOptions describes the set of type options for diagnostic
+// messages, e.g. `boolean, one of "enabled", "disabled", or object`.
+func describeOptions(opts []*TypeOption) string {
+	var descs []string
+	seen := make(map[string]bool)
+	for _, o := range opts {
+		d := describeOption(o)
+		if !seen[d] {
+			seen[d] = true
+			descs = append(descs, d)
+		}
+	}
+	switch len(descs) {
+	case 0:
+		return "nothing"
+	case 1:
+		return descs[0]
+	case 2:
+		return descs[0] + " or " + descs[1]
+	}
+	return strings.Join(descs[:len(descs)-1], ", ") + ", or " + descs[len(descs)-1]
+}
+
+func describeOption(o *TypeOption) string {
+	d := o.Type
+	if len(o.Choices) > 0 && o.Type != "boolean" {
+		var cs []string
+		for _, c := range o.Choices {
+			cs = append(cs, fmt.Sprintf("%q", c))
+		}
+		d = "one of " + strings.Join(cs, ", ")
+	}
+	switch {
+	case o.Array:
+		d = fmt.Sprintf("array(%s)", d)
+	case o.Map:
+		d = fmt.Sprintf("map(%s)", d)
+	case o.ArrayOfMap:
+		d = fmt.Sprintf("array(map(%s))", d)
+	case o.MapOfArray:
+		d = fmt.Sprintf("map(array(%s))", d)
+	case o.MapOfMap:
+		d = fmt.Sprintf("map(map(%s))", d)
+	case o.ArrayOfArray:
+		d = fmt.Sprintf("array(array(%s))", d)
+	}
+	return d
+}
+
+// oneLine collapses text onto a single line.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// suggestProperty returns the property name or alias closest to the
+// unknown key, if it is likely a typo.
+func suggestProperty(key string, idx map[string]*Property) string {
+	key = strings.ToLower(key)
+	best := ""
+	bestDist := len(key)/4 + 1
+	for name := range idx {
+		d := editDistance(key, name)
+		if d < bestDist || (d == bestDist && best != "" && name < best) {
+			best = name
+			bestDist = d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between two strings
+// where a transposition of adjacent characters counts as one edit.
+func editDistance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = minInt(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = minInt(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+func minInt(n int, ns ...int) int {
+	for _, x := range ns {
+		if x < n {
+			n = x
+		}
+	}
+	return n
+}
@@ -0,0 +1,212 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// validateConf parses and validates the conf against the default config,
+// returning the diagnostics as strings.
+func validateConf(t *testing.T, src string) []string {
+	t.Helper()
+
+	cfg, err := DefaultConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := ParseConf("test.conf", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var diags []string
+	for _, d := range ResolveVariables(doc, &ResolveOptions{Env: map[string]string{}}) {
+		diags = append(diags, d.String())
+	}
+	for _, d := range ValidateDocument(cfg, doc) {
+		diags = append(diags, d.String())
+	}
+	return diags
+}
+
+// validateTest is a conf and the diagnostics expected from validating it.
+type validateTest struct {
+	name string
+	src  string
+	want []string
+}
+
+func runValidateTests(t *testing.T, tests []validateTest) {
+	t.Helper()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := validateConf(t, tt.src)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got diagnostics:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	runValidateTests(t, []validateTest{
+		{
+			name: "valid",
+			src:  "port: 4222\nhost: \"0.0.0.0\"\njetstream {max_mem: 1G, store_dir: /data}\nwrite_deadline: \"2s\"\n",
+		},
+		{
+			name: "unknown property",
+			src:  "prot: 4222",
+			want: []string{`test.conf:1:1: error: unknown property "prot", did you mean "port"?`},
+		},
+		{
+			name: "wrong type",
+			src:  `port: "abc"`,
+			want: []string{`test.conf:1:7: error: invalid value "abc" (string) for "port": expected integer`},
+		},
+		{
+			name: "choices",
+			src:  "jetstream {cipher: des}",
+			want: []string{`test.conf:1:20: error: invalid value des (string) for "jetstream.cipher": expected one of "chacha", "chachapoly", "aes"`},
+		},
+		{
+			name: "duration",
+			src:  `write_deadline: "10 seconds"`,
+			want: []string{`test.conf:1:17: error: invalid value "10 seconds" (string) for "write_deadline": expected duration`},
+		},
+		{
+			name: "storage",
+			src:  "max_payload: 1XB",
+			want: []string{`test.conf:1:14: error: invalid value 1XB (string) for "max_payload": expected storage`},
+		},
+		{
+			name: "alias path",
+			src:  "jetstream {max_mem: [1]}",
+			want: []string{`test.conf:1:21: error: invalid value array for "jetstream.max_memory_store": expected storage`},
+		},
+		{
+			name: "union",
+			src:  "server_tags: {a: b}",
+			want: []string{`test.conf:1:14: error: invalid value map for "server_tags": expected string or array(string)`},
+		},
+		{
+			name: "deprecated",
+			src:  "store_dir: /data",
+			want: []string{"test.conf:1:1: warning: property \"store_dir\" is deprecated, use \"jetstream.store_dir\" instead: Define `store_dir` under the top-level `jetstream` block."},
+		},
+		{
+			name: "variables",
+			src:  "PORT: 4222\nport: $PORT\nhttp_port: $NOPE",
+			want: []string{`test.conf:3:12: error: undefined variable "NOPE"`},
+		},
+	})
+}
+
+func TestValidateWeights(t *testing.T) {
+	runValidateTests(t, []validateTest{
+		{
+			name: "valid",
+			src:  `mappings { "a.>": [{destination: b.>, weight: 60%}, {destination: c.>, weight: 40%}] }`,
+		},
+		{
+			name: "over",
+			src:  `mappings { "a.>": [{destination: b.>, weight: 60%}, {destination: c.>, weight: 60%}] }`,
+			want: []string{`test.conf:1:34: error: weights of the destinations of "a.>" not scoped to a cluster add up to 120%, more than 100%: "b.>" (60%), "c.>" (60%)`},
+		},
+		{
+			name: "under per cluster",
+			src:  `mappings { "a.>": [{destination: b.>}, {destination: c.>, weight: 40%, cluster: west}] }`,
+			want: []string{`test.conf:1:54: warning: weights of the destinations of "a.>" in cluster "west" add up to 40%, the remaining 60% of messages are dropped: "c.>" (40%)`},
+		},
+		{
+			name: "invalid destinations",
+			src:  `mappings { "a.>": [{weight: 50%}, {destination: y, weight: 150%}] }`,
+			want: []string{
+				`test.conf:1:20: error: mapping destination has no destination`,
+				`test.conf:1:60: error: weight 150 is not between 0 and 100`,
+			},
+		},
+		{
+			name: "invalid destination in another cluster",
+			src:  `mappings { "a.>": [{weight: 50%, cluster: east}, {destination: y, weight: 40%}] }`,
+			want: []string{
+				`test.conf:1:20: error: mapping destination has no destination`,
+				`test.conf:1:64: warning: weights of the destinations of "a.>" not scoped to a cluster add up to 40%, the remaining 60% of messages are dropped: "y" (40%)`,
+			},
+		},
+	})
+}
+
+func TestValidateAliasConflicts(t *testing.T) {
+	runValidateTests(t, []validateTest{
+		{
+			name: "alias",
+			src:  "leaf {port: 7422}",
+		},
+		{
+			name: "alias and name",
+			src:  "leaf {port: 1}\nleafnodes {port: 2}",
+			want: []string{`test.conf:2:1: error: "leafnodes" conflicts with "leaf" at test.conf:1:1: both set property "leafnodes"`},
+		},
+		{
+			name: "nested",
+			src:  "jetstream {max_mem: 1G, max_memory_store: 2G}",
+			want: []string{`test.conf:1:25: error: "max_memory_store" conflicts with "max_mem" at test.conf:1:12: both set property "jetstream.max_memory_store"`},
+		},
+	})
+}
+
+func TestValidateConstraints(t *testing.T) {
+	runValidateTests(t, []validateTest{
+		{
+			name: "satisfied",
+			src:  "authorization {user: a, password: b}",
+		},
+		{
+			name: "requires",
+			src:  "authorization {user: a}",
+			want: []string{`test.conf:1:16: error: property "user" requires "password"`},
+		},
+		{
+			name: "exclusive",
+			src:  "authorization {user: a, password: b, token: c}",
+			want: []string{
+				`test.conf:1:38: error: "token" cannot be set along with "user" at test.conf:1:16`,
+				`test.conf:1:38: error: "token" cannot be set along with "password" at test.conf:1:25`,
+			},
+		},
+		{
+			name: "one of required",
+			src:  "accounts {A {exports: [{stream: a.>}, {stream: b, service: c}, {accounts: [B]}]}}",
+			want: []string{
+				`test.conf:1:51: error: "service" cannot be set along with "stream" at test.conf:1:40`,
+				`test.conf:1:64: error: one of "stream" or "service" is required`,
+			},
+		},
+	})
+}
+
+func TestValidateAppliesWhen(t *testing.T) {
+	runValidateTests(t, []validateTest{
+		{
+			name: "applies",
+			src:  `resolver {type: cache, ttl: "1h"}`,
+		},
+		{
+			name: "other value",
+			src:  `resolver {type: full, dir: x, ttl: "1h"}`,
+			want: []string{`test.conf:1:31: warning: "ttl" is ignored when "type" is "full", it only applies when "type" is "cache"`},
+		},
+		{
+			name: "unset",
+			src:  `resolver {dir: x, ttl: "1h"}`,
+			want: []string{`test.conf:1:19: warning: "ttl" is ignored unless "type" is "cache"`},
+		},
+		{
+			name: "property set",
+			src:  "accounts {A {imports: [{stream: {account: B, subject: a}, to: b}]}}",
+			want: []string{`test.conf:1:59: warning: "to" is ignored unless "service" is set`},
+		},
+	})
+}
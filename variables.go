@@ -0,0 +1,160 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// ResolveOptions configures how variables are resolved.
+type ResolveOptions struct {
+	// Env are the environment variables a variable falls back to if it is
+	// not defined in the conf file. If nil, the environment of the process
+	// is used.
+	Env map[string]string
+}
+
+// quotedVariableRe matches a string which looks like a variable reference.
+var quotedVariableRe = regexp.MustCompile(`^\$[A-Za-z0-9_-]+$`)
+
+// ResolveVariables resolves the variable references in the document,
+// including resolved includes, and sets the value each one refers to.
+// Variables are block-scoped: a reference resolves to a key defined before
+// it in the same map or an enclosing map, where the innermost definition
+// wins, falling back to the environment. Quoted strings are never
+// interpolated. Diagnostics are returned for undefined variables, inner
+// definitions shadowing outer ones, environment values starting with a
+// number, and quoted references.
+func ResolveVariables(doc *Document, opts *ResolveOptions) []Diagnostic {
+	lookupEnv := os.LookupEnv
+	if opts != nil && opts.Env != nil {
+		lookupEnv = func(name string) (string, bool) {
+			v, ok := opts.Env[name]
+			return v, ok
+		}
+	}
+
+	r := variableResolver{
+		lookupEnv: lookupEnv,
+		shadowed:  make(map[*Entry]bool),
+	}
+	r.resolveMap(doc.Root)
+	return r.diags
+}
+
+type variableResolver struct {
+	lookupEnv func(string) (string, bool)
+
+	// scopes are the variables defined in the enclosing maps, innermost
+	// last.
+	scopes []map[string]*Entry
+
+	// shadowed are the definitions already reported as shadowing another.
+	shadowed map[*Entry]bool
+
+	diags []Diagnostic
+}
+
+func (r *variableResolver) add(pos Pos, sev Severity, format string, args ...any) {
+	r.diags = append(r.diags, Diagnostic{
+		Pos:      pos,
+		Severity: sev,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (r *variableResolver) resolveMap(m *Value) {
+	scope := make(map[string]*Entry)
+	r.scopes = append(r.scopes, scope)
+
+	for _, e := range mapEntries(m) {
+		if e.Include {
+			continue
+		}
+		r.resolveValue(e.Value)
+		scope[e.Key] = e
+	}
+
+	r.scopes = r.scopes[:len(r.scopes)-1]
+}
+
+func (r *variableResolver) resolveValue(v *Value) {
+	switch v.Kind {
+	case MapValue:
+		r.resolveMap(v)
+
+	case ArrayValue:
+		for _, x := range v.Elems {
+			r.resolveValue(x)
+		}
+
+	case VariableValue:
+		r.resolveVariable(v)
+
+	case StringValue:
+		if v.Quote != 0 && v.Quote != '(' && quotedVariableRe.MatchString(v.Str) {
+			name := v.Str[1:]
+			_, env := r.lookupEnv(name)
+			if r.lookup(name) != nil || env {
+				r.add(v.Pos, SeverityWarning, "quoted string %q is not a variable reference, remove the quotes to reference the variable", v.Str)
+			}
+		}
+	}
+}
+
+// lookup returns the innermost definition of the variable, if any.
+func (r *variableResolver) lookup(name string) *Entry {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if e, ok := r.scopes[i][name]; ok {
+			return e
+		}
+	}
+	return nil
+}
+
+func (r *variableResolver) resolveVariable(v *Value) {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		def, ok := r.scopes[i][v.Str]
+		if !ok {
+			continue
+		}
+
+		// Report an inner definition hiding an outer one once.
+		for j := i - 1; j >= 0; j-- {
+			if outer, ok := r.scopes[j][v.Str]; ok && !r.shadowed[def] {
+				r.shadowed[def] = true
+				r.add(def.KeyPos, SeverityWarning, "variable %q shadows the definition at %s", v.Str, outer.KeyPos)
+				break
+			}
+		}
+
+		v.Def = def
+
+		// A variable may refer to another variable.
+		if def.Value.Kind == VariableValue {
+			v.Resolved = def.Value.Resolved
+		} else {
+			v.Resolved = def.Value
+		}
+		return
+	}
+
+	s, ok := r.lookupEnv(v.Str)
+	if !ok {
+		r.add(v.Pos, SeverityError, "undefined variable %q", v.Str)
+		return
+	}
+
+	if s != "" && s[0] >= '0' && s[0] <= '9' {
+		r.add(v.Pos, SeverityWarning, "value %q of environment variable %q begins with a number and may be parsed as a number", s, v.Str)
+	}
+
+	// Environment values are parsed as conf values.
+	x := &Value{Kind: StringValue, Raw: s, Str: s}
+	if doc, err := ParseConf("", []byte("v: "+s)); err == nil && len(doc.Root.Entries) == 1 {
+		x = doc.Root.Entries[0].Value
+	}
+	x.Pos = v.Pos
+	x.End = v.End
+	v.Resolved = x
+}
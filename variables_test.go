@@ -0,0 +1,87 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestResolveVariables(t *testing.T) {
+	const src = `A: 1
+a {
+  x: $A
+  A: 2
+  y: $A
+  b { z: $A }
+}
+w: $A
+e: $ENV_NUM
+f: $ENV_STR
+g: "$A"
+h: $NOPE
+i: $B
+B: 3
+`
+
+	doc, err := ParseConf("test.conf", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	diags := ResolveVariables(doc, &ResolveOptions{Env: map[string]string{
+		"ENV_NUM": "1abc",
+		"ENV_STR": "hello",
+	}})
+
+	wantDiags := []string{
+		`test.conf:4:3: warning: variable "A" shadows the definition at test.conf:1:1`,
+		`test.conf:9:4: warning: value "1abc" of environment variable "ENV_NUM" begins with a number and may be parsed as a number`,
+		`test.conf:11:4: warning: quoted string "$A" is not a variable reference, remove the quotes to reference the variable`,
+		`test.conf:12:4: error: undefined variable "NOPE"`,
+		// Variables must be defined before they are referenced.
+		`test.conf:13:4: error: undefined variable "B"`,
+	}
+	var got []string
+	for _, d := range diags {
+		got = append(got, d.String())
+	}
+	if strings.Join(got, "\n") != strings.Join(wantDiags, "\n") {
+		t.Errorf("diagnostics:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(wantDiags, "\n"))
+	}
+
+	// The value each reference resolves to, where `env` marks values from
+	// the environment.
+	want := map[string]string{
+		"x": "1",
+		"y": "2",
+		"z": "2",
+		"w": "1",
+		"e": "1abc env",
+		"f": "hello env",
+		"h": "",
+		"i": "",
+	}
+	var walk func(m *Value)
+	walk = func(m *Value) {
+		for _, e := range m.Entries {
+			switch e.Value.Kind {
+			case MapValue:
+				walk(e.Value)
+			case VariableValue:
+				var s string
+				if r := e.Value.Resolved; r != nil {
+					s = r.Raw
+					if e.Value.Def == nil {
+						s += " env"
+					}
+				}
+				if s != want[e.Key] {
+					t.Errorf("$%s of %q resolved to %q, want %q", e.Value.Str, e.Key, s, want[e.Key])
+				}
+				delete(want, e.Key)
+			}
+		}
+	}
+	walk(doc.Root)
+	for k := range want {
+		t.Errorf("no variable reference found for %q", k)
+	}
+}
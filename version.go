@@ -0,0 +1,172 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseVersion parses a server version such as `2.9.0`, `2.10` or `v2.2`.
+// Missing minor and patch components are zero.
+func parseVersion(s string) ([3]int, error) {
+	var v [3]int
+
+	parts := strings.Split(strings.TrimPrefix(strings.TrimSpace(s), "v"), ".")
+	if len(parts) > 3 {
+		return v, fmt.Errorf("invalid version %q", s)
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("invalid version %q", s)
+		}
+		v[i] = n
+	}
+
+	return v, nil
+}
+
+// compareVersions returns -1, 0, or 1 if version a is less than, equal to,
+// or greater than version b.
+func compareVersions(a, b [3]int) int {
+	for i := range a {
+		switch {
+		case a[i] < b[i]:
+			return -1
+		case a[i] > b[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+// FilterVersion returns a copy of the config having only the properties
+// valid for the server version, i.e. introduced at or before the version
+// and not yet removed. Deprecation notes of properties deprecated after
+// the version are dropped.
+func FilterVersion(c *Config, version string) (*Config, error) {
+	v, err := parseVersion(version)
+	if err != nil {
+		return nil, err
+	}
+
+	f := versionFilter{
+		version: v,
+		opts:    make(map[*TypeOption]*TypeOption),
+	}
+
+	sections, err := f.sections(c.Sections)
+	if err != nil {
+		return nil, err
+	}
+
+	x := *c
+	x.Sections = sections
+	return &x, nil
+}
+
+// versionFilter copies the parsed config, omitting the properties not
+// valid for the version.
+type versionFilter struct {
+	version [3]int
+
+	// opts maps the original type options to their copies so references
+	// to recursive types are copied only once.
+	opts map[*TypeOption]*TypeOption
+}
+
+func (f *versionFilter) sections(sections []*Section) ([]*Section, error) {
+	var xs []*Section
+	for _, s := range sections {
+		x := *s
+		x.Properties = nil
+		for _, p := range s.Properties {
+			xp, err := f.property(p)
+			if err != nil {
+				return nil, err
+			}
+			if xp != nil {
+				x.Properties = append(x.Properties, xp)
+			}
+		}
+		xs = append(xs, &x)
+	}
+	return xs, nil
+}
+
+// property returns a copy of the property or nil if it is not valid
+// for the version.
+func (f *versionFilter) property(p *Property) (*Property, error) {
+	// compare compares the filter version to a property version, where
+	// an empty version is considered to be before any version.
+	compare := func(s string) (int, error) {
+		if s == "" {
+			return 1, nil
+		}
+		v, err := parseVersion(s)
+		if err != nil {
+			return 0, fmt.Errorf("property %q: %w", p.Name, err)
+		}
+		return compareVersions(f.version, v), nil
+	}
+
+	if c, err := compare(p.Version); err != nil || c < 0 {
+		return nil, err
+	}
+	if p.RemovedIn != "" {
+		if c, err := compare(p.RemovedIn); err != nil || c >= 0 {
+			return nil, err
+		}
+	}
+
+	x := *p
+	if p.DeprecatedIn != "" {
+		c, err := compare(p.DeprecatedIn)
+		if err != nil {
+			return nil, err
+		}
+		if c < 0 {
+			x.Deprecation = ""
+			x.DeprecatedIn = ""
+		}
+	}
+
+	x.Types = nil
+	for _, o := range p.Types {
+		xo, err := f.option(o)
+		if err != nil {
+			return nil, err
+		}
+		x.Types = append(x.Types, xo)
+	}
+
+	return &x, nil
+}
+
+func (f *versionFilter) option(o *TypeOption) (*TypeOption, error) {
+	if x, ok := f.opts[o]; ok {
+		return x, nil
+	}
+
+	x := *o
+	f.opts[o] = &x
+
+	sections, err := f.sections(o.Sections)
+	if err != nil {
+		return nil, err
+	}
+	x.Sections = sections
+
+	if o.Target != nil {
+		x.Target = nil
+		for _, t := range o.Target {
+			xt, err := f.option(t)
+			if err != nil {
+				return nil, err
+			}
+			x.Target = append(x.Target, xt)
+		}
+	}
+
+	return &x, nil
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		s    string
+		want [3]int
+		err  bool
+	}{
+		{s: "2.9.0", want: [3]int{2, 9, 0}},
+		{s: "2.10", want: [3]int{2, 10, 0}},
+		{s: "v2.2", want: [3]int{2, 2, 0}},
+		{s: " 2 ", want: [3]int{2, 0, 0}},
+		{s: "2.9.0.1", err: true},
+		{s: "2.x", err: true},
+		{s: "", err: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseVersion(tt.s)
+		if (err != nil) != tt.err {
+			t.Errorf("parseVersion(%q): error %v, want error %v", tt.s, err, tt.err)
+			continue
+		}
+		if err == nil && got != tt.want {
+			t.Errorf("parseVersion(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+
+	if c := compareVersions([3]int{2, 10, 0}, [3]int{2, 9, 7}); c != 1 {
+		t.Errorf("compareVersions(2.10.0, 2.9.7) = %d, want 1", c)
+	}
+}
+
+func TestFilterVersion(t *testing.T) {
+	const configYAML = `
+sections:
+  - name: Server
+    properties:
+      port:
+        type: integer
+      websocket:
+        type: websocket
+      old:
+        type: string
+        removed_in: 2.10.0
+      legacy:
+        type: string
+        deprecated_in: 2.9.0
+        deprecation: Use port.
+`
+	const typesYAML = `
+types:
+  websocket:
+    type: object
+    version: 2.2.0
+    properties:
+      port:
+        type: integer
+      compression:
+        type: boolean
+        version: 2.9.0
+`
+
+	c, err := parseTestConfig(configYAML, typesYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{"2.1", "port old legacy"},
+		{"2.2", "port websocket(port) old legacy"},
+		{"2.9.0", "port websocket(port compression) old legacy(deprecated)"},
+		{"2.10.0", "port websocket(port compression) legacy(deprecated)"},
+	}
+
+	// describe lists the properties, nested ones in parentheses.
+	var describe func(sections []*Section) string
+	describe = func(sections []*Section) string {
+		var names []string
+		for _, s := range sections {
+			for _, p := range s.Properties {
+				n := p.Name
+				if p.Deprecation != "" {
+					n += "(deprecated)"
+				}
+				if len(p.Types[0].Sections) > 0 {
+					n += "(" + describe(p.Types[0].Sections) + ")"
+				}
+				names = append(names, n)
+			}
+		}
+		return strings.Join(names, " ")
+	}
+
+	for _, tt := range tests {
+		f, err := FilterVersion(c, tt.version)
+		if err != nil {
+			t.Errorf("FilterVersion(%s): %v", tt.version, err)
+			continue
+		}
+		if got := describe(f.Sections); got != tt.want {
+			t.Errorf("FilterVersion(%s) = %s, want %s", tt.version, got, tt.want)
+		}
+	}
+
+	// The original config is left as is.
+	if got := describe(c.Sections); got != "port websocket(port compression) old legacy(deprecated)" {
+		t.Errorf("original config changed: %s", got)
+	}
+
+	if _, err := FilterVersion(c, "latest"); err == nil {
+		t.Error("no error for an invalid version")
+	}
+}